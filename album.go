@@ -1,15 +1,38 @@
 package gphoto
 
 type googleAlbumResponse struct {
-	GoogleAlbums []*GoogleAlbum `json:"albums"`
+	GoogleAlbums  []*GoogleAlbum `json:"albums"`
+	NextPageToken string         `json:"nextPageToken"`
+}
+
+type sharedAlbumResponse struct {
+	SharedAlbums  []*GoogleAlbum `json:"sharedAlbums"`
+	NextPageToken string         `json:"nextPageToken"`
 }
 
 // GoogleAlbum represent google album structure received from api.
 type GoogleAlbum struct {
-	ID                    string `json:"id"`
-	Title                 string `json:"title"`
-	ProductURL            string `json:"productUrl"`
-	MediaItemsCount       string `json:"mediaItemsCount"`
-	CoverPhotoBaseURL     string `json:"coverPhotoBaseUrl"`
-	CoverPhotoMediaItemID string `json:"coverPhotoMediaItemId"`
+	ID                    string     `json:"id"`
+	Title                 string     `json:"title"`
+	ProductURL            string     `json:"productUrl"`
+	MediaItemsCount       string     `json:"mediaItemsCount"`
+	CoverPhotoBaseURL     string     `json:"coverPhotoBaseUrl"`
+	CoverPhotoMediaItemID string     `json:"coverPhotoMediaItemId"`
+	ShareInfo             *ShareInfo `json:"shareInfo,omitempty"`
+}
+
+// ShareInfo represent sharing state of the album, it is set only for shared albums.
+type ShareInfo struct {
+	SharedAlbumOptions SharedAlbumOptions `json:"sharedAlbumOptions"`
+	ShareableURL       string             `json:"shareableUrl"`
+	ShareToken         string             `json:"shareToken"`
+	IsJoined           bool               `json:"isJoined"`
+	IsOwned            bool               `json:"isOwned"`
+	IsJoinable         bool               `json:"isJoinable"`
+}
+
+// SharedAlbumOptions control what other users can do in the shared album.
+type SharedAlbumOptions struct {
+	IsCollaborative bool `json:"isCollaborative"`
+	IsCommentable   bool `json:"isCommentable"`
 }
@@ -0,0 +1,60 @@
+package gphoto
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Scopes of Google Photos Library API.
+const (
+	ScopeLibrary = "https://www.googleapis.com/auth/photoslibrary"
+	ScopeSharing = "https://www.googleapis.com/auth/photoslibrary.sharing"
+)
+
+const authURL = "https://accounts.google.com/o/oauth2/auth"
+
+var exchangeCodeErr = errors.New("can`t exchange authorization code")
+
+// Token represent OAuth tokens received for an authorization code.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
+	TokenType    string `json:"token_type"`
+}
+
+// AuthCodeURL build consent page url, the user is redirected to redirectURI with an authorization code.
+// Offline access is requested so the code can be exchanged for a refresh token.
+func AuthCodeURL(clientID, redirectURI string, scopes ...string) string {
+	if len(scopes) == 0 {
+		scopes = []string{ScopeLibrary, ScopeSharing}
+	}
+
+	query := url.Values{}
+	query.Set("client_id", clientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("response_type", "code")
+	query.Set("access_type", "offline")
+	query.Set("prompt", "consent")
+	query.Set("scope", strings.Join(scopes, " "))
+	return authURL + "?" + query.Encode()
+}
+
+// ExchangeAuthCode exchange authorization code received on redirectURI for the tokens.
+func ExchangeAuthCode(clientID, clientSecret, code, redirectURI string, opts ...Option) (*Token, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	token, err := newGoogleApi(o).exchangeCode(clientID, clientSecret, code, redirectURI)
+	if err != nil {
+		logrus.WithError(err).Errorln(exchangeCodeErr)
+		return nil, exchangeCodeErr
+	}
+	return token, nil
+}
@@ -0,0 +1,109 @@
+package gphoto
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	cacheNotSupportedErr = errors.New("repository does not support cache management")
+	cacheErr             = errors.New("cache error")
+)
+
+// cacheManager is implemented by repositories able to report and clear cached data.
+type cacheManager interface {
+	stats() (*CacheStats, error)
+	clear() error
+}
+
+// CacheStats represent cached data summary.
+type CacheStats struct {
+	Albums      int   `json:"albums"`
+	Photos      int   `json:"photos"`
+	SyncRecords int   `json:"syncRecords"`
+	Bytes       int64 `json:"bytes"`
+}
+
+// CacheStats report cached data summary.
+func (c *Client) CacheStats() (*CacheStats, error) {
+	cache, ok := c.repo.(cacheManager)
+	if !ok {
+		return nil, cacheNotSupportedErr
+	}
+
+	stats, err := cache.stats()
+	if err != nil {
+		logrus.WithError(err).Errorln(cacheErr)
+		return nil, cacheErr
+	}
+	return stats, nil
+}
+
+// ClearCache drop all cached albums and photos, sync records are kept.
+func (c *Client) ClearCache() error {
+	cache, ok := c.repo.(cacheManager)
+	if !ok {
+		return cacheNotSupportedErr
+	}
+
+	if err := cache.clear(); err != nil {
+		logrus.WithError(err).Errorln(cacheErr)
+		return cacheErr
+	}
+	return nil
+}
+
+// stats count cached albums, photos and sync records.
+func (r *BoltRepository) stats() (*CacheStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := new(CacheStats)
+
+	err := r.view(func(tx *bbolt.Tx) error {
+		stats.Bytes = tx.Size()
+
+		if pBucket := r.buckets(tx).Bucket([]byte(photoBucket)); pBucket != nil {
+			err := pBucket.ForEach(func(k, v []byte) error {
+				albumBucket := pBucket.Bucket(k)
+				if albumBucket == nil {
+					return nil
+				}
+				stats.Albums++
+				stats.Photos += albumBucket.Stats().KeyN
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if bucket := r.buckets(tx).Bucket([]byte(syncBucket)); bucket != nil {
+			stats.SyncRecords = bucket.Stats().KeyN
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// clear recreate photo, album and access buckets.
+func (r *BoltRepository) clear() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.update(func(tx *bbolt.Tx) error {
+		for _, name := range []string{photoBucket, albumBucket, accessBucket} {
+			if r.buckets(tx).Bucket([]byte(name)) != nil {
+				if err := r.buckets(tx).DeleteBucket([]byte(name)); err != nil {
+					return err
+				}
+			}
+			if _, err := r.buckets(tx).CreateBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+		logrus.Debugln("cache cleared")
+		return nil
+	})
+}
@@ -0,0 +1,350 @@
+package gphoto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Modes of the Recorder.
+const (
+	// ModeRecord send requests to the network and store interactions in the cassette.
+	ModeRecord RecorderMode = iota
+	// ModeReplay answer requests from the cassette without network access.
+	ModeReplay
+)
+
+const (
+	cassetteVersion = 1
+	redacted        = "REDACTED"
+	base64Encoding  = "base64"
+)
+
+var (
+	cassetteErr     = errors.New("cassette error")
+	cassetteMissErr = errors.New("no recorded interaction for the request")
+)
+
+// redactedFields are form, query and JSON fields holding secrets.
+var redactedFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"client_secret": true,
+	"code":          true,
+}
+
+// redactedHeaders are headers holding secrets.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// RecorderMode select whether the Recorder records or replays traffic.
+type RecorderMode int
+
+// Cassette is a recorded sequence of HTTP interactions.
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request with its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request with secrets redacted.
+type RecordedRequest struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     string      `json:"body,omitempty"`
+	Encoding string      `json:"encoding,omitempty"`
+}
+
+// RecordedResponse is a response with secrets redacted.
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Encoding   string      `json:"encoding,omitempty"`
+}
+
+// Recorder is an http.RoundTripper recording Google API traffic into a cassette file
+// and replaying it later, Authorization headers, tokens and client secrets are redacted.
+type Recorder struct {
+	mode     RecorderMode
+	path     string
+	next     http.RoundTripper
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// NewRecorder make Recorder for the cassette file at path.
+// In ModeReplay the cassette is loaded, in ModeRecord requests are sent with next
+// or http.DefaultTransport if it is nil, and the cassette is written by Save.
+func NewRecorder(path string, mode RecorderMode, next http.RoundTripper) (*Recorder, error) {
+	r := &Recorder{
+		mode:     mode,
+		path:     path,
+		next:     next,
+		cassette: Cassette{Version: cassetteVersion},
+	}
+	if r.next == nil {
+		r.next = http.DefaultTransport
+	}
+	if mode != ModeReplay {
+		return r, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		logrus.WithError(err).Errorln(cassetteErr)
+		return nil, cassetteErr
+	}
+	if err = json.Unmarshal(data, &r.cassette); err != nil {
+		logrus.WithError(err).Errorln(cassetteErr)
+		return nil, cassetteErr
+	}
+	if r.cassette.Version != cassetteVersion {
+		logrus.WithField("version", r.cassette.Version).Errorln(cassetteErr)
+		return nil, cassetteErr
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// RoundTrip record or replay the request.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	interaction := &Interaction{Request: *recorded, Response: RecordedResponse{
+		StatusCode: res.StatusCode,
+		Header:     redactHeader(res.Header),
+	}}
+	interaction.Response.Body, interaction.Response.Encoding = encodeBody(redactBody(res.Header.Get("Content-Type"), body))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+	return res, nil
+}
+
+// Save write recorded interactions to the cassette file.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err == nil {
+		err = ioutil.WriteFile(r.path, data, 0600)
+	}
+	if err != nil {
+		logrus.WithError(err).Errorln(cassetteErr)
+		return cassetteErr
+	}
+	return nil
+}
+
+// Cassette return a copy of recorded or loaded interactions.
+func (r *Recorder) Cassette() Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.cassette
+	c.Interactions = append([]*Interaction(nil), r.cassette.Interactions...)
+	return c
+}
+
+// replay answer with the first unused interaction matching method, url and body of the request.
+func (r *Recorder) replay(req *http.Request, recorded *RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !interaction.Request.matches(recorded) {
+			continue
+		}
+		r.used[i] = true
+
+		body, err := decodeBody(interaction.Response.Body, interaction.Response.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		header := interaction.Response.Header
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	logrus.WithFields(logrus.Fields{"method": recorded.Method, "url": recorded.URL}).Errorln(cassetteMissErr)
+	return nil, cassetteMissErr
+}
+
+func (r *RecordedRequest) matches(other *RecordedRequest) bool {
+	return r.Method == other.Method && r.URL == other.URL && r.Body == other.Body && r.Encoding == other.Encoding
+}
+
+// recordRequest make redacted copy of the request, its body is restored for sending.
+func recordRequest(req *http.Request) (*RecordedRequest, error) {
+	recorded := &RecordedRequest{
+		Method: req.Method,
+		URL:    redactURL(req.URL),
+		Header: redactHeader(req.Header),
+	}
+	if req.Body == nil {
+		return recorded, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	recorded.Body, recorded.Encoding = encodeBody(redactBody(req.Header.Get("Content-Type"), body))
+	return recorded, nil
+}
+
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	changed := false
+	for key := range query {
+		if redactedFields[key] {
+			query.Set(key, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = query.Encode()
+	return c.String()
+}
+
+func redactHeader(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	c := make(http.Header, len(header))
+	for key, values := range header {
+		c[key] = append([]string(nil), values...)
+	}
+	for _, key := range redactedHeaders {
+		if c.Get(key) != "" {
+			c.Set(key, redacted)
+		}
+	}
+	return c
+}
+
+// redactBody replace secrets of form and JSON bodies, JSON is redacted at any depth, other bodies are kept as is.
+func redactBody(contentType string, body []byte) []byte {
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return body
+		}
+		for key := range form {
+			if redactedFields[key] {
+				form.Set(key, redacted)
+			}
+		}
+		return []byte(form.Encode())
+	case strings.HasPrefix(contentType, "application/json"):
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return body
+		}
+		if !redactJSON(v) {
+			return body
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return body
+		}
+		return data
+	}
+	return body
+}
+
+// redactJSON replace secrets of decoded JSON in place at any depth, it report whether anything was replaced.
+func redactJSON(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if redactedFields[key] {
+				v[key] = redacted
+				changed = true
+				continue
+			}
+			if redactJSON(value) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if redactJSON(value) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// encodeBody keep text bodies readable, binary ones are base64 encoded.
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), base64Encoding
+}
+
+func decodeBody(body, encoding string) ([]byte, error) {
+	if encoding == base64Encoding {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
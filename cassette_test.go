@@ -0,0 +1,155 @@
+package gphoto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "albums.json")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			_, _ = w.Write([]byte(`{"access_token":"secret-access","expires_in":3600}`))
+		case "/v1/albums":
+			if r.Header.Get("Authorization") != "Bearer secret-access" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"albums":[{"id":"album","title":"Holidays"}]}`))
+		case "/v1/uploads":
+			_, _ = w.Write([]byte("upload-token"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	recorder, err := NewRecorder(path, ModeRecord, nil)
+	require.NoError(t, err)
+	api := newGoogleApi(&options{libraryURL: server.URL, tokenURL: server.URL + "/token", transport: recorder})
+
+	token, err := api.refreshAccessToken("client", "client-secret", "secret-refresh")
+	require.NoError(t, err)
+	assert.Equal(t, "secret-access", token, "the client receive unredacted response")
+	albums, err := api.getAlbumList(token)
+	require.NoError(t, err)
+	uploadToken, err := api.uploadMedia(token, "image.png", bytes.NewReader([]byte{0x89, 'P', 'N', 'G', 0xff}))
+	require.NoError(t, err)
+	require.NoError(t, recorder.Save())
+	server.Close()
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	for _, secret := range []string{"secret-access", "secret-refresh", "client-secret"} {
+		assert.NotContains(t, string(data), secret)
+	}
+	assert.Len(t, recorder.Cassette().Interactions, 3)
+
+	player, err := NewRecorder(path, ModeReplay, nil)
+	require.NoError(t, err)
+	api = newGoogleApi(&options{libraryURL: server.URL, tokenURL: server.URL + "/token", transport: player})
+
+	token, err = api.refreshAccessToken("client", "other-secret", "other-refresh")
+	require.NoError(t, err)
+	assert.Equal(t, redacted, token)
+	replayed, err := api.getAlbumList(token)
+	require.NoError(t, err)
+	assert.Equal(t, albums, replayed)
+	replayedToken, err := api.uploadMedia(token, "image.png", bytes.NewReader([]byte{0x89, 'P', 'N', 'G', 0xff}))
+	require.NoError(t, err)
+	assert.Equal(t, uploadToken, replayedToken)
+
+	_, err = api.getAlbumList(token)
+	assert.Error(t, err, "every interaction is replayed once")
+}
+
+func TestNewRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "missing file", wantErr: true},
+		{name: "invalid json", content: "{", wantErr: true},
+		{name: "unknown version", content: `{"version":2}`, wantErr: true},
+		{name: "empty cassette", content: `{"version":1,"interactions":[]}`},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i)))
+			if tt.content != "" {
+				require.NoError(t, ioutil.WriteFile(path, []byte(tt.content), 0600))
+			}
+			_, err := NewRecorder(path, ModeReplay, nil)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func Test_redactBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "client_id=id&client_secret=secret&grant_type=refresh_token&refresh_token=token",
+			want:        "client_id=id&client_secret=REDACTED&grant_type=refresh_token&refresh_token=REDACTED",
+		},
+		{
+			name:        "json",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"access_token":"token","expires_in":3600}`,
+			want:        `{"access_token":"REDACTED","expires_in":3600}`,
+		},
+		{
+			name:        "nested json",
+			contentType: "application/json",
+			body:        `{"auth":{"code":"code","tokens":[{"id_token":"token"}]},"size":12345678901234567890}`,
+			want:        `{"auth":{"code":"REDACTED","tokens":[{"id_token":"REDACTED"}]},"size":12345678901234567890}`,
+		},
+		{
+			name:        "authorization code form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "code=secret&grant_type=authorization_code",
+			want:        "code=REDACTED&grant_type=authorization_code",
+		},
+		{
+			name:        "json without secrets",
+			contentType: "application/json",
+			body:        `{"id": "album"}`,
+			want:        `{"id": "album"}`,
+		},
+		{
+			name:        "other",
+			contentType: "text/plain",
+			body:        "access_token=token",
+			want:        "access_token=token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(redactBody(tt.contentType, []byte(tt.body))))
+		})
+	}
+}
@@ -1,7 +1,11 @@
 package gphoto
 
 import (
+	"context"
 	"errors"
+	"io"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	bolt "go.etcd.io/bbolt"
@@ -12,30 +16,68 @@ type api interface {
 	getAlbumList(accessToken string) ([]*GoogleAlbum, error)
 	searchPhotos(accessToken, albumID string) ([]*GooglePhoto, error)
 	urlIsValid(url string) bool
+	listMediaItems(accessToken string) ([]*GooglePhoto, error)
+	listSharedAlbums(accessToken string) ([]*GoogleAlbum, error)
+	joinSharedAlbum(accessToken, shareToken string) (*GoogleAlbum, error)
+	leaveSharedAlbum(accessToken, shareToken string) error
+	shareAlbum(accessToken, albumID string, options SharedAlbumOptions) (*ShareInfo, error)
+	unshareAlbum(accessToken, albumID string) error
+	addEnrichment(accessToken, albumID string, enrichment NewEnrichmentItem, position AlbumPosition) (string, error)
+	patchMediaItem(accessToken, mediaID, description string) (*GooglePhoto, error)
+	patchAlbum(accessToken, albumID, title string) (*GoogleAlbum, error)
+	download(url string, offset int64) (io.ReadCloser, bool, error)
+	getMediaItem(accessToken, mediaID string) (*GooglePhoto, error)
+	searchMedia(accessToken string, filter SearchFilter) ([]*GooglePhoto, error)
+	uploadMedia(accessToken, fileName string, content io.Reader) (string, error)
+	createMediaItem(accessToken, albumID, uploadToken, fileName, description string) (*GooglePhoto, error)
 }
 
-type repository interface {
-	savePhotos(album string, photo []*GooglePhoto) error
-	listPhotos(album string) ([]*GooglePhoto, error)
-	truncateAlbum(album string) error
-	close() error
+// Repository is a storage of cached albums and album photos.
+type Repository interface {
+	SavePhotos(album string, photo []*GooglePhoto) error
+	ListPhotos(album string) ([]*GooglePhoto, error)
+	TruncateAlbum(album string) error
+	UpdatePhoto(photo *GooglePhoto) error
+	SaveAlbums(albums []*GoogleAlbum) error
+	ListAlbums() ([]*GoogleAlbum, error)
+	GetAlbum(albumID string) (*GoogleAlbum, error)
+	Close() error
 }
 
-// Client struct.
+// Client struct, it is safe for concurrent use.
 type Client struct {
+	// mu guard tokens, validated and the album list kept in memory of the origin Client.
+	mu           sync.Mutex
 	clientID     string
 	clientSecret string
 	accessToken  string
 	refreshToken string
 	api          api
-	repo         repository
+	repo         Repository
+	photoTTL     time.Duration
+	albumTTL     time.Duration
+	validated    map[string]time.Time
+	albums       []*GoogleAlbum
+	albumsAt     time.Time
+	limits       CacheLimits
+	evictStop    chan struct{}
+	evictDone    chan struct{}
+	metrics      Metrics
+	tracer       Tracer
+	ctx          context.Context
+	base         *Client
+	limiter      *rateLimiter
 }
 
+// ErrPhotoNotExists is returned for media items unknown to the api.
+var ErrPhotoNotExists = errors.New("photo not exists")
+
 // Some api errors.
 var (
 	refreshTokenErr = errors.New("can`t refresh token")
 	searchPhotosErr = errors.New("search photos error")
 	getAlbumErr     = errors.New("get album error")
+	getPhotoErr     = errors.New("get photo error")
 	truncateErr     = errors.New("truncate album error")
 	saveErr         = errors.New("save album error")
 	initDbErr       = errors.New("bolt DB init error")
@@ -43,93 +85,243 @@ var (
 )
 
 // NewGoogleClient create google photo api Client.
-func NewGoogleClient(clientID, clientSecret, refreshToken string) (*Client, error) {
-	db, err := initDB(googlePhotoDB)
-	if err != nil {
-		logrus.WithError(err).Errorln(initDbErr)
-		return nil, initDbErr
+func NewGoogleClient(clientID, clientSecret, refreshToken string, opts ...Option) (*Client, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	repo := o.repo
+	if repo == nil {
+		db, err := initDB(o.dbPath)
+		if err != nil {
+			logrus.WithError(err).Errorln(initDbErr)
+			return nil, initDbErr
+		}
+		if repo, err = newBoltRepository(db, o.keys, ""); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
 	}
 
-	repo, err := NewBoltRepository(db)
+	api := newGoogleApi(o)
+	c := newClient(clientID, clientSecret, refreshToken, api, repo, o)
+	store, _ := repo.(quotaStore)
+	c.limiter = newRateLimiter(o.rateLimits, store)
+	api.limiter = c.limiter
+	c.startEviction()
+	return c, nil
+}
+
+// newClient make Client of the account, its rate limiter is not set and eviction is not started.
+func newClient(clientID, clientSecret, refreshToken string, api *googleApi, repo Repository, o *options) *Client {
 	return &Client{
 		clientID:     clientID,
 		clientSecret: clientSecret,
 		refreshToken: refreshToken,
-		api:          NewGoogleApi(),
+		api:          api,
 		repo:         repo,
-	}, err
+		photoTTL:     o.photoTTL,
+		albumTTL:     o.albumTTL,
+		validated:    make(map[string]time.Time),
+		limits:       o.limits,
+		metrics:      o.metrics,
+		tracer:       o.tracer,
+	}
 }
 
 // GetAlbumList fetch all photo albums.
-func (c *Client) GetAlbumList() ([]*GoogleAlbum, error) {
-	albums, err := c.api.getAlbumList(c.accessToken)
-	if err == unauthorizedErr {
-		c.accessToken, err = c.api.refreshAccessToken(c.clientID, c.clientSecret, c.refreshToken)
-		if err != nil {
-			logrus.WithError(err).Error(refreshTokenErr)
-			return albums, refreshTokenErr
-		}
-		albums, err = c.api.getAlbumList(c.accessToken)
-		if err != nil {
-			logrus.WithError(err).Errorln(getAlbumErr)
-			return albums, getAlbumErr
-		}
+func (c *Client) GetAlbumList() (albums []*GoogleAlbum, err error) {
+	c, span, end := c.startSpan("gphoto.GetAlbumList")
+	defer func() { end(err, Attr(AttrItemCount, len(albums))) }()
+
+	memo := c.origin()
+	memo.mu.Lock()
+	memoAlbums, memoAt := memo.albums, memo.albumsAt
+	memo.mu.Unlock()
+	if c.albumTTL > 0 && time.Since(memoAt) < c.albumTTL {
+		logrus.Debugln("album list served from memory")
+		c.observeCacheLookup(span, CacheAlbums, CacheHit)
+		return memoAlbums, nil
+	}
+
+	if c.albumTTL > 0 {
+		c.observeCacheLookup(span, CacheAlbums, CacheMiss)
+	}
+
+	err = c.withAuth(func(accessToken string) error {
+		var err error
+		albums, err = c.api.getAlbumList(accessToken)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return albums, err
 	} else if err != nil {
 		logrus.WithError(err).Errorln(getAlbumErr)
 		return albums, getAlbumErr
 	}
-	return albums, err
+
+	err = c.traceRepo("SaveAlbums", "", func() error {
+		return c.repo.SaveAlbums(albums)
+	})
+	if err != nil {
+		logrus.WithError(err).Errorln(saveErr)
+		return albums, saveErr
+	}
+	if c.albumTTL > 0 {
+		memo.mu.Lock()
+		memo.albums, memo.albumsAt = albums, time.Now()
+		memo.mu.Unlock()
+	}
+	return albums, nil
 }
 
 // GetPhotoByAlbum fetch photos of a specific album.
-func (c *Client) GetPhotoByAlbum(albumID string) ([]*GooglePhoto, error) {
-	var (
-		photos []*GooglePhoto
-		err    error
-	)
+func (c *Client) GetPhotoByAlbum(albumID string) (photos []*GooglePhoto, err error) {
+	c, span, end := c.startSpan("gphoto.GetPhotoByAlbum", Attr(AttrAlbumID, albumID))
+	defer func() { end(err, Attr(AttrItemCount, len(photos))) }()
+
+	err = c.traceRepo("ListPhotos", albumID, func() error {
+		photos, err = c.repo.ListPhotos(albumID)
+		return err
+	})
+	if err == nil && len(photos) > 0 && c.photoTTL > 0 && time.Since(c.validatedAt(albumID)) < c.photoTTL {
+		logrus.WithField("album", albumID).Debugln("album photos served from cache within ttl")
+		c.observeCacheLookup(span, CachePhotos, CacheHit)
+		return photos, nil
+	}
 
-	photos, err = c.repo.listPhotos(albumID)
 	urlIsValid := len(photos) > 0 && c.api.urlIsValid(photos[0].BaseURL)
 	logrus.WithField("isValid", urlIsValid).Debugln("first photo url is valid")
 	if err == nil && urlIsValid {
+		c.observeCacheLookup(span, CachePhotos, CacheHit)
+		c.markValidated(albumID)
 		return photos, nil
 	} else {
-		photos, err = c.api.searchPhotos(c.accessToken, albumID)
-		if err == unauthorizedErr {
-			c.accessToken, err = c.api.refreshAccessToken(c.clientID, c.clientSecret, c.refreshToken)
-			if err != nil {
-				logrus.WithError(err).Error(refreshTokenErr)
-				return photos, refreshTokenErr
-			}
-			photos, err = c.api.searchPhotos(c.accessToken, albumID)
-			if err != nil {
-				logrus.WithError(err).Errorln(searchPhotosErr)
-				return photos, searchPhotosErr
-			}
+		if err == nil && len(photos) > 0 {
+			c.observeCacheLookup(span, CachePhotos, CacheStale)
+		} else {
+			c.observeCacheLookup(span, CachePhotos, CacheMiss)
+		}
+		err = c.withAuth(func(accessToken string) error {
+			photos, err = c.api.searchPhotos(accessToken, albumID)
+			return err
+		})
+		if err == refreshTokenErr || err == ErrQuotaExceeded {
+			return photos, err
+		} else if err == notFoundErr {
+			logrus.WithField("album", albumID).Debugln(ErrAlbumNotExists)
+			return nil, ErrAlbumNotExists
 		} else if err != nil {
 			logrus.WithError(err).Errorln(searchPhotosErr)
 			return photos, searchPhotosErr
 		}
 
-		if err = c.repo.truncateAlbum(albumID); err != nil {
+		err = c.traceRepo("TruncateAlbum", albumID, func() error {
+			return c.repo.TruncateAlbum(albumID)
+		})
+		if err != nil {
 			logrus.WithError(err).Error(truncateErr)
 			return photos, truncateErr
 		}
 
 		if len(photos) > 0 {
-			if err = c.repo.savePhotos(albumID, photos); err != nil {
+			err = c.traceRepo("SavePhotos", albumID, func() error {
+				return c.repo.SavePhotos(albumID, photos)
+			})
+			if err != nil {
 				logrus.WithError(err).Errorln(saveErr)
 				return photos, saveErr
 			}
 		}
+		c.markValidated(albumID)
 	}
 
 	return photos, err
 }
 
-// Close DB repository connection.
+// GetPhoto fetch the media item with a fresh base url, the cache is not used.
+func (c *Client) GetPhoto(mediaID string) (photo *GooglePhoto, err error) {
+	c, _, end := c.startSpan("gphoto.GetPhoto", Attr(AttrMediaID, mediaID))
+	defer func() { end(err) }()
+
+	err = c.withAuth(func(accessToken string) error {
+		var err error
+		photo, err = c.api.getMediaItem(accessToken, mediaID)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return nil, err
+	} else if err == notFoundErr {
+		return nil, ErrPhotoNotExists
+	} else if err != nil {
+		logrus.WithError(err).WithField("media", mediaID).Errorln(getPhotoErr)
+		return nil, getPhotoErr
+	}
+	return photo, nil
+}
+
+// markValidated remember when album photo urls were known to be valid.
+func (c *Client) markValidated(albumID string) {
+	state := c.origin()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if c.photoTTL > 0 && state.validated != nil {
+		state.validated[albumID] = time.Now()
+	}
+}
+
+// validatedAt return when album photo urls were known to be valid, zero time if never.
+func (c *Client) validatedAt(albumID string) time.Time {
+	state := c.origin()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.validated[albumID]
+}
+
+// withAuth run api call with current access token,
+// the token is refreshed once if api respond with unauthorizedErr.
+// Concurrent calls rejected with the same token wait for a single refresh.
+func (c *Client) withAuth(call func(accessToken string) error) error {
+	auth := c.origin()
+	auth.mu.Lock()
+	accessToken := auth.accessToken
+	auth.mu.Unlock()
+
+	err := call(accessToken)
+	if err != unauthorizedErr {
+		return err
+	}
+
+	var refreshErr error
+	auth.mu.Lock()
+	if auth.accessToken == accessToken {
+		traced, _, end := c.startSpan("gphoto.refreshToken")
+		auth.accessToken, refreshErr = traced.api.refreshAccessToken(c.clientID, c.clientSecret, auth.refreshToken)
+		end(refreshErr)
+		c.observeTokenRefresh(refreshErr == nil)
+	}
+	accessToken = auth.accessToken
+	auth.mu.Unlock()
+	if refreshErr != nil {
+		logrus.WithError(refreshErr).Error(refreshTokenErr)
+		return refreshTokenErr
+	}
+	return call(accessToken)
+}
+
+// Close DB repository connection, background cache eviction is stopped
+// and counted requests are written to the quota ledger.
 func (c *Client) Close() error {
-	return c.repo.close()
+	c.origin().stopEviction()
+	if c.limiter != nil {
+		if err := c.limiter.flush(); err != nil {
+			logrus.WithError(err).Errorln(quotaErr)
+		}
+	}
+	return c.repo.Close()
 }
 
 // initDB init Bolt database connection.
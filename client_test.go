@@ -2,8 +2,13 @@ package gphoto
 
 import (
 	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -24,13 +29,16 @@ var (
 	repoMock = new(MockedRepo)
 
 	setupSavePhotos = func(err error) {
-		repoMock.On("savePhotos", mock.Anything, mock.Anything).Return(err).Once()
+		repoMock.On("SavePhotos", mock.Anything, mock.Anything).Return(err).Once()
 	}
 	setupTruncateAlbum = func(err error) {
-		repoMock.On("truncateAlbum", mock.Anything).Return(err).Once()
+		repoMock.On("TruncateAlbum", mock.Anything).Return(err).Once()
 	}
 	setupListPhotos = func(list []*GooglePhoto, err error) {
-		repoMock.On("listPhotos", mock.Anything).Return(list, err).Once()
+		repoMock.On("ListPhotos", mock.Anything).Return(list, err).Once()
+	}
+	setupSaveAlbums = func(err error) {
+		repoMock.On("SaveAlbums", mock.Anything).Return(err).Once()
 	}
 
 	setupRefreshAccessToken = func(token string, err error) {
@@ -47,23 +55,44 @@ var (
 	}
 )
 
-func (m MockedRepo) savePhotos(album string, photo []*GooglePhoto) error {
+func (m *MockedRepo) SavePhotos(album string, photo []*GooglePhoto) error {
 	args := m.Called(album, photo)
 	return args.Error(0)
 }
 
-func (m MockedRepo) listPhotos(album string) ([]*GooglePhoto, error) {
+func (m *MockedRepo) ListPhotos(album string) ([]*GooglePhoto, error) {
 	args := m.Called(album)
 	return args.Get(0).([]*GooglePhoto), args.Error(1)
 }
 
-func (m MockedRepo) truncateAlbum(album string) error {
+func (m *MockedRepo) TruncateAlbum(album string) error {
 	args := m.Called(album)
 	return args.Error(0)
 
 }
 
-func (m MockedRepo) close() error {
+func (m *MockedRepo) UpdatePhoto(photo *GooglePhoto) error {
+	args := m.Called(photo)
+	return args.Error(0)
+}
+
+func (m *MockedRepo) SaveAlbums(albums []*GoogleAlbum) error {
+	args := m.Called(albums)
+	return args.Error(0)
+}
+
+func (m *MockedRepo) ListAlbums() ([]*GoogleAlbum, error) {
+	args := m.Called()
+	return args.Get(0).([]*GoogleAlbum), args.Error(1)
+}
+
+func (m *MockedRepo) GetAlbum(albumID string) (*GoogleAlbum, error) {
+	args := m.Called(albumID)
+	album, _ := args.Get(0).(*GoogleAlbum)
+	return album, args.Error(1)
+}
+
+func (m *MockedRepo) Close() error {
 	args := m.Called()
 	return args.Error(0)
 }
@@ -88,6 +117,102 @@ func (m *MockedApi) urlIsValid(url string) bool {
 	return args.Bool(0)
 }
 
+func (m *MockedApi) listMediaItems(accessToken string) ([]*GooglePhoto, error) {
+	args := m.Called(accessToken)
+	return args.Get(0).([]*GooglePhoto), args.Error(1)
+}
+
+func (m *MockedApi) download(url string, offset int64) (io.ReadCloser, bool, error) {
+	args := m.Called(url, offset)
+	body, _ := args.Get(0).(io.ReadCloser)
+	return body, args.Bool(1), args.Error(2)
+}
+
+func (m *MockedApi) listSharedAlbums(accessToken string) ([]*GoogleAlbum, error) {
+	args := m.Called(accessToken)
+	return args.Get(0).([]*GoogleAlbum), args.Error(1)
+}
+
+func (m *MockedApi) joinSharedAlbum(accessToken, shareToken string) (*GoogleAlbum, error) {
+	args := m.Called(accessToken, shareToken)
+	album, _ := args.Get(0).(*GoogleAlbum)
+	return album, args.Error(1)
+}
+
+func (m *MockedApi) leaveSharedAlbum(accessToken, shareToken string) error {
+	args := m.Called(accessToken, shareToken)
+	return args.Error(0)
+}
+
+func (m *MockedApi) shareAlbum(accessToken, albumID string, options SharedAlbumOptions) (*ShareInfo, error) {
+	args := m.Called(accessToken, albumID, options)
+	shareInfo, _ := args.Get(0).(*ShareInfo)
+	return shareInfo, args.Error(1)
+}
+
+func (m *MockedApi) unshareAlbum(accessToken, albumID string) error {
+	args := m.Called(accessToken, albumID)
+	return args.Error(0)
+}
+
+func (m *MockedApi) addEnrichment(accessToken, albumID string, enrichment NewEnrichmentItem, position AlbumPosition) (string, error) {
+	args := m.Called(accessToken, albumID, enrichment, position)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockedApi) patchMediaItem(accessToken, mediaID, description string) (*GooglePhoto, error) {
+	args := m.Called(accessToken, mediaID, description)
+	photo, _ := args.Get(0).(*GooglePhoto)
+	return photo, args.Error(1)
+}
+
+func (m *MockedApi) patchAlbum(accessToken, albumID, title string) (*GoogleAlbum, error) {
+	args := m.Called(accessToken, albumID, title)
+	album, _ := args.Get(0).(*GoogleAlbum)
+	return album, args.Error(1)
+}
+
+func (m *MockedApi) searchMedia(accessToken string, filter SearchFilter) ([]*GooglePhoto, error) {
+	args := m.Called(accessToken, filter)
+	return args.Get(0).([]*GooglePhoto), args.Error(1)
+}
+
+func (m *MockedApi) uploadMedia(accessToken, fileName string, content io.Reader) (string, error) {
+	args := m.Called(accessToken, fileName, content)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockedApi) createMediaItem(accessToken, albumID, uploadToken, fileName, description string) (*GooglePhoto, error) {
+	args := m.Called(accessToken, albumID, uploadToken, fileName, description)
+	photo, _ := args.Get(0).(*GooglePhoto)
+	return photo, args.Error(1)
+}
+
+func (m *MockedApi) getMediaItem(accessToken, mediaID string) (*GooglePhoto, error) {
+	args := m.Called(accessToken, mediaID)
+	photo, _ := args.Get(0).(*GooglePhoto)
+	return photo, args.Error(1)
+}
+
+// newBoltClient create client with the mocked api and a bolt repository in a temporary directory.
+func newBoltClient(t *testing.T, api *MockedApi) (*Client, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "gphoto-client")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	db, err := initDB(filepath.Join(dir, "test.db"))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	repo, err := NewBoltRepository(db)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return &Client{accessToken: "ACCESS_TOKEN", api: api, repo: repo}, dir
+}
+
 func TestClient_GetAlbumList(t *testing.T) {
 	type fields struct {
 		clientID     string
@@ -126,6 +251,7 @@ func TestClient_GetAlbumList(t *testing.T) {
 			wantErr: nil,
 			setup: func() {
 				setupGetAlbumList(list, nil)
+				setupSaveAlbums(nil)
 			},
 		},
 		{
@@ -142,6 +268,7 @@ func TestClient_GetAlbumList(t *testing.T) {
 				setupGetAlbumList(list, unauthorizedErr)
 				setupRefreshAccessToken("token", nil)
 				setupGetAlbumList(list, nil)
+				setupSaveAlbums(nil)
 			},
 		},
 		{
@@ -222,7 +349,7 @@ func TestClient_GetPhotoByAlbum(t *testing.T) {
 		accessToken  string
 		refreshToken string
 		api          api
-		repo         repository
+		repo         Repository
 	}
 
 	type args struct {
@@ -414,3 +541,64 @@ func TestClient_GetPhotoByAlbum(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_GetPhoto(t *testing.T) {
+	api := new(MockedApi)
+	c, dir := newBoltClient(t, api)
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	tests := []struct {
+		name    string
+		photo   *GooglePhoto
+		apiErr  error
+		wantErr error
+	}{
+		{name: "success", photo: &GooglePhoto{ID: "photo", BaseURL: "http://photo/fresh"}},
+		{name: "not found", apiErr: notFoundErr, wantErr: ErrPhotoNotExists},
+		{name: "api fail", apiErr: someErr, wantErr: getPhotoErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api.On("getMediaItem", "ACCESS_TOKEN", "photo").Return(tt.photo, tt.apiErr).Once()
+
+			got, err := c.GetPhoto("photo")
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.photo, got)
+		})
+	}
+	api.AssertExpectations(t)
+}
+
+func TestClient_cacheTTL(t *testing.T) {
+	api := new(MockedApi)
+	repo := new(MockedRepo)
+	c := &Client{
+		accessToken: "ACCESS_TOKEN",
+		api:         api,
+		repo:        repo,
+		photoTTL:    time.Hour,
+		albumTTL:    time.Hour,
+		validated:   make(map[string]time.Time),
+	}
+	photos := []*GooglePhoto{{ID: "abcdef", BaseURL: "http://photo.com"}}
+	albums := []*GoogleAlbum{{ID: "album"}}
+
+	repo.On("ListPhotos", "album").Return(photos, nil).Twice()
+	api.On("urlIsValid", "http://photo.com").Return(true).Once()
+	api.On("getAlbumList", "ACCESS_TOKEN").Return(albums, nil).Once()
+	repo.On("SaveAlbums", albums).Return(nil).Once()
+
+	for i := 0; i < 2; i++ {
+		got, err := c.GetPhotoByAlbum("album")
+		assert.NoError(t, err)
+		assert.Equal(t, photos, got)
+
+		gotAlbums, err := c.GetAlbumList()
+		assert.NoError(t, err)
+		assert.Equal(t, albums, gotAlbums)
+	}
+
+	api.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ihippik/gphoto"
+	"github.com/ihippik/gphoto/gphotogallery"
+)
+
+// app hold state shared by commands.
+type app struct {
+	cfg    *gphoto.Config
+	out    *output
+	stderr io.Writer
+	client *gphoto.Client
+}
+
+// googleClient create api client on first use.
+func (a *app) googleClient() (*gphoto.Client, error) {
+	if a.client != nil {
+		return a.client, nil
+	}
+	if a.cfg.RefreshToken == "" {
+		return nil, errors.New("refresh token is required, run gphoto auth to obtain one")
+	}
+
+	client, err := gphoto.NewClientFromConfig(a.cfg)
+	if err != nil {
+		return nil, err
+	}
+	a.client = client
+	return client, nil
+}
+
+func (a *app) close() {
+	if a.client != nil {
+		_ = a.client.Close()
+	}
+}
+
+// flagSet create command flag set writing errors to stderr.
+func (a *app) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	return fs
+}
+
+func runAuth(a *app, args []string) error {
+	fs := a.flagSet("auth")
+	port := fs.Int("port", 0, "local port of the redirect listener, random when 0")
+	timeout := fs.Duration("timeout", 5*time.Minute, "time to wait for the consent")
+	if err := fs.Parse(args); err != nil {
+		return usageErr
+	}
+	if a.cfg.ClientID == "" || a.cfg.ClientSecret == "" {
+		return errors.New("client id and client secret are required")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(*port))
+	if err != nil {
+		return err
+	}
+	redirectURI := "http://" + listener.Addr().String() + "/"
+
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if msg := req.URL.Query().Get("error"); msg != "" {
+			http.Error(rw, "authorization failed: "+msg, http.StatusBadRequest)
+			errs <- errors.New("authorization failed: " + msg)
+			return
+		}
+		code := req.URL.Query().Get("code")
+		if code == "" {
+			http.NotFound(rw, req)
+			return
+		}
+		_, _ = fmt.Fprintln(rw, "Authorization received, you can close this window.")
+		codes <- code
+	})}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer func() {
+		_ = server.Close()
+	}()
+
+	_, _ = fmt.Fprintf(a.stderr, "Open the following link in your browser:\n\n%s\n\n", gphoto.AuthCodeURL(a.cfg.ClientID, redirectURI))
+
+	var code string
+	select {
+	case code = <-codes:
+	case err = <-errs:
+		return err
+	case <-time.After(*timeout):
+		return errors.New("authorization timed out")
+	}
+
+	token, err := gphoto.ExchangeAuthCode(a.cfg.ClientID, a.cfg.ClientSecret, code, redirectURI)
+	if err != nil {
+		return err
+	}
+	return a.out.write(token,
+		[]string{"REFRESH TOKEN", "ACCESS TOKEN", "EXPIRES IN", "SCOPE"},
+		[][]string{{token.RefreshToken, token.AccessToken, strconv.Itoa(token.ExpiresIn), token.Scope}},
+	)
+}
+
+func runAlbums(a *app, args []string) error {
+	if err := a.flagSet("albums").Parse(args); err != nil {
+		return usageErr
+	}
+	client, err := a.googleClient()
+	if err != nil {
+		return err
+	}
+
+	albums, err := client.GetAlbumList()
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(albums))
+	for _, album := range albums {
+		rows = append(rows, []string{album.ID, album.Title, album.MediaItemsCount})
+	}
+	return a.out.write(albums, []string{"ID", "TITLE", "ITEMS"}, rows)
+}
+
+func runPhotos(a *app, args []string) error {
+	fs := a.flagSet("photos")
+	if err := fs.Parse(args); err != nil {
+		return usageErr
+	}
+	if fs.NArg() != 1 {
+		_, _ = fmt.Fprintln(a.stderr, "usage: gphoto photos <album>")
+		return usageErr
+	}
+	client, err := a.googleClient()
+	if err != nil {
+		return err
+	}
+
+	photos, err := client.GetPhotoByAlbum(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return a.writePhotos(photos)
+}
+
+func runSearch(a *app, args []string) error {
+	var filter gphoto.SearchFilter
+
+	fs := a.flagSet("search")
+	from := fs.String("from", "", "creation date from, YYYY-MM-DD")
+	to := fs.String("to", "", "creation date to, YYYY-MM-DD")
+	categories := fs.String("category", "", "comma separated content categories, e.g. LANDSCAPES,PETS")
+	fs.StringVar(&filter.MediaType, "type", "", "media type: ALL_MEDIA, PHOTO or VIDEO")
+	fs.BoolVar(&filter.Favorites, "favorites", false, "only favorite media items")
+	if err := fs.Parse(args); err != nil {
+		return usageErr
+	}
+
+	var err error
+	if *from != "" {
+		if filter.From, err = time.Parse("2006-01-02", *from); err != nil {
+			return fmt.Errorf("invalid -from date: %s", err)
+		}
+	}
+	if *to != "" {
+		if filter.To, err = time.Parse("2006-01-02", *to); err != nil {
+			return fmt.Errorf("invalid -to date: %s", err)
+		}
+	}
+	if *categories != "" {
+		filter.Categories = strings.Split(*categories, ",")
+	}
+
+	client, err := a.googleClient()
+	if err != nil {
+		return err
+	}
+	photos, err := client.Search(filter)
+	if err != nil {
+		return err
+	}
+	return a.writePhotos(photos)
+}
+
+func runDownload(a *app, args []string) error {
+	var opts gphoto.SyncOptions
+
+	fs := a.flagSet("download")
+	fs.StringVar(&opts.Dir, "dir", ".", "local mirror directory")
+	layout := fs.String("layout", "date", "layout: date, album or a custom template, e.g. {year}/{album}/{filename}")
+	albums := fs.String("album", "", "comma separated album IDs, the whole library when empty")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "only report what would be downloaded")
+	verify := fs.Bool("verify", false, "verify local copies instead of downloading new items")
+	refetch := fs.Bool("refetch", false, "with -verify fetch missing or corrupt copies again")
+	if err := fs.Parse(args); err != nil {
+		return usageErr
+	}
+
+	switch *layout {
+	case "date":
+		opts.Layout = gphoto.LayoutByDate
+	case "album":
+		opts.Layout = gphoto.LayoutByAlbum
+	default:
+		opts.Layout = *layout
+	}
+	if *albums != "" {
+		opts.Albums = strings.Split(*albums, ",")
+	}
+
+	client, err := a.googleClient()
+	if err != nil {
+		return err
+	}
+
+	if *verify {
+		report, err := client.VerifySync(opts.Dir, *refetch)
+		if err != nil {
+			return err
+		}
+		var rows [][]string
+		for _, record := range report.Missing {
+			rows = append(rows, []string{"missing", record.MediaID, record.Path})
+		}
+		for _, record := range report.Corrupt {
+			rows = append(rows, []string{"corrupt", record.MediaID, record.Path})
+		}
+		for _, record := range report.Refetched {
+			rows = append(rows, []string{"refetched", record.MediaID, record.Path})
+		}
+		return a.out.write(report, []string{"STATUS", "ID", "PATH"}, rows)
+	}
+
+	report, err := client.Sync(opts)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(report.Fetched))
+	for _, record := range report.Fetched {
+		rows = append(rows, []string{record.MediaID, record.Path, strconv.FormatInt(record.Size, 10)})
+	}
+	if err = a.out.write(report, []string{"ID", "PATH", "SIZE"}, rows); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(a.stderr, "fetched: %d, skipped: %d, bytes: %d\n", len(report.Fetched), report.Skipped, report.Bytes)
+	return nil
+}
+
+func runUpload(a *app, args []string) error {
+	fs := a.flagSet("upload")
+	album := fs.String("album", "", "album ID to add uploaded items to")
+	description := fs.String("description", "", "description of uploaded items")
+	if err := fs.Parse(args); err != nil {
+		return usageErr
+	}
+	if fs.NArg() == 0 {
+		_, _ = fmt.Fprintln(a.stderr, "usage: gphoto upload [-album id] [-description text] <file>...")
+		return usageErr
+	}
+	client, err := a.googleClient()
+	if err != nil {
+		return err
+	}
+
+	photos := make([]*gphoto.GooglePhoto, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		photo, err := client.UploadFile(*album, path, *description)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		photos = append(photos, photo)
+	}
+	return a.writePhotos(photos)
+}
+
+func runCache(a *app, args []string) error {
+	const usage = "usage: gphoto cache stats|clear|evict|compact|export <file>|import <file>"
+
+	fs := a.flagSet("cache")
+	if err := fs.Parse(args); err != nil {
+		return usageErr
+	}
+	action := fs.Arg(0)
+	wantArgs := 1
+	if action == "export" || action == "import" {
+		wantArgs = 2
+	}
+	if fs.NArg() != wantArgs {
+		_, _ = fmt.Fprintln(a.stderr, usage)
+		return usageErr
+	}
+	client, err := a.googleClient()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "stats":
+		stats, err := client.CacheStats()
+		if err != nil {
+			return err
+		}
+		return a.out.write(stats,
+			[]string{"ALBUMS", "PHOTOS", "SYNC RECORDS", "BYTES"},
+			[][]string{{
+				strconv.Itoa(stats.Albums),
+				strconv.Itoa(stats.Photos),
+				strconv.Itoa(stats.SyncRecords),
+				strconv.FormatInt(stats.Bytes, 10),
+			}},
+		)
+	case "clear":
+		return client.ClearCache()
+	case "evict":
+		evicted, err := client.EvictCache()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(a.stderr, "%d albums evicted\n", evicted)
+		return err
+	case "compact":
+		return client.CompactCache()
+	case "export":
+		f, err := os.Create(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		if err = client.ExportCache(f); err != nil {
+			_ = f.Close()
+			return err
+		}
+		return f.Close()
+	case "import":
+		f, err := os.Open(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return client.ImportCache(f)
+	default:
+		_, _ = fmt.Fprintln(a.stderr, usage)
+		return usageErr
+	}
+}
+
+// writePhotos render media items.
+func (a *app) writePhotos(photos []*gphoto.GooglePhoto) error {
+	rows := make([][]string, 0, len(photos))
+	for _, photo := range photos {
+		rows = append(rows, []string{
+			photo.ID,
+			photo.Filename,
+			photo.MimeType,
+			photo.MediaMetadata.CreationTime.Format(time.RFC3339),
+			strconv.FormatInt(photo.MediaMetadata.Width, 10),
+			strconv.FormatInt(photo.MediaMetadata.Height, 10),
+		})
+	}
+	return a.out.write(photos, []string{"ID", "FILENAME", "TYPE", "CREATED", "WIDTH", "HEIGHT"}, rows)
+}
+
+func runQuota(a *app, args []string) error {
+	fs := a.flagSet("quota")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return usageErr
+	}
+	client, err := a.googleClient()
+	if err != nil {
+		return err
+	}
+
+	usage, err := client.Quota()
+	if err != nil {
+		return err
+	}
+	return a.out.write(usage,
+		[]string{"DAY", "READS", "WRITES", "LIMIT", "REMAINING", "BATCH REMAINING"},
+		[][]string{{
+			usage.Day,
+			strconv.Itoa(usage.Reads),
+			strconv.Itoa(usage.Writes),
+			strconv.Itoa(usage.Limit),
+			strconv.Itoa(usage.Remaining),
+			strconv.Itoa(usage.BatchRemaining),
+		}},
+	)
+}
+
+func runGallery(a *app, args []string) error {
+	var opts gphotogallery.Options
+
+	fs := a.flagSet("gallery")
+	fs.StringVar(&opts.Dir, "dir", "gallery", "site directory")
+	fs.StringVar(&opts.Title, "title", "", "title of the index page")
+	fs.StringVar(&opts.Templates, "templates", "", "directory of *.html templates overriding the default ones")
+	albums := fs.String("album", "", "comma separated album IDs, every album when empty")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return usageErr
+	}
+	if *albums != "" {
+		opts.Albums = strings.Split(*albums, ",")
+	}
+
+	client, err := a.googleClient()
+	if err != nil {
+		return err
+	}
+
+	report, err := gphotogallery.Generate(client, opts)
+	if err != nil {
+		return err
+	}
+	return a.out.write(report,
+		[]string{"ALBUMS", "PHOTOS", "DOWNLOADED", "WRITTEN", "UNCHANGED", "REMOVED"},
+		[][]string{{
+			strconv.Itoa(report.Albums),
+			strconv.Itoa(report.Photos),
+			strconv.Itoa(report.Downloaded),
+			strconv.Itoa(report.Written),
+			strconv.Itoa(report.Unchanged),
+			strconv.Itoa(report.Removed),
+		}},
+	)
+}
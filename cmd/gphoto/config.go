@@ -0,0 +1,37 @@
+package main
+
+import "github.com/ihippik/gphoto"
+
+// envConfig hold path of the config file used when -config flag is omitted.
+const envConfig = "GPHOTO_CONFIG"
+
+// resolveConfig combine flags, environment variables and YAML or JSON config file,
+// in that order of precedence.
+func resolveConfig(flags gphoto.Config, configPath string) (*gphoto.Config, error) {
+	cfg := gphoto.DefaultConfig()
+	if configPath != "" {
+		var err error
+		if cfg, err = gphoto.LoadConfig(configPath); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.LoadEnv(); err != nil {
+		return nil, err
+	}
+
+	for _, field := range []struct {
+		flag  string
+		value *string
+	}{
+		{flag: flags.ClientID, value: &cfg.ClientID},
+		{flag: flags.ClientSecret, value: &cfg.ClientSecret},
+		{flag: flags.RefreshToken, value: &cfg.RefreshToken},
+		{flag: flags.DBPath, value: &cfg.DBPath},
+		{flag: flags.LogLevel, value: &cfg.LogLevel},
+	} {
+		if field.flag != "" {
+			*field.value = field.flag
+		}
+	}
+	return cfg, nil
+}
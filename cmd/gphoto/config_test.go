@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ihippik/gphoto"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_resolveConfig(t *testing.T) {
+	file, err := ioutil.TempFile("", "gphoto-config-*.yaml")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("client_id: file-id\nclient_secret: file-secret\nrefresh_token: file-token\ndb_path: file.db\n")
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	assert.NoError(t, os.Setenv(gphoto.EnvClientSecret, "env-secret"))
+	defer os.Unsetenv(gphoto.EnvClientSecret)
+
+	cfg, err := resolveConfig(gphoto.Config{ClientID: "flag-id"}, file.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "flag-id", cfg.ClientID)
+	assert.Equal(t, "env-secret", cfg.ClientSecret)
+	assert.Equal(t, "file-token", cfg.RefreshToken)
+	assert.Equal(t, "file.db", cfg.DBPath)
+
+	_, err = resolveConfig(gphoto.Config{}, file.Name()+".missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+// Command gphoto is a command-line client of Google Photos Library API.
+//
+// Usage:
+//
+//	gphoto [global flags] <command> [command flags] [args]
+//
+// Commands:
+//
+//	auth               obtain a refresh token through the browser consent page
+//	albums             list albums
+//	photos <album>     list photos of the album
+//	search             search library media items
+//	download           mirror media items into a local directory
+//	upload <file>...   upload files into the library
+//	cache <action>     stats, clear, evict, compact, export or import the local cache
+//	quota              show API requests made today and the remaining daily budget
+//	gallery            write albums as a static HTML gallery
+//
+// Settings are read from flags, GPHOTO_* environment variables or a YAML or JSON
+// config file, in that order of precedence.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ihippik/gphoto"
+	"github.com/sirupsen/logrus"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(app *app, args []string) error
+}
+
+var commands = []command{
+	{name: "auth", usage: "obtain a refresh token through the browser consent page", run: runAuth},
+	{name: "albums", usage: "list albums", run: runAlbums},
+	{name: "photos", usage: "list photos of the album: photos <album>", run: runPhotos},
+	{name: "search", usage: "search library media items", run: runSearch},
+	{name: "download", usage: "mirror media items into a local directory", run: runDownload},
+	{name: "upload", usage: "upload files into the library: upload <file>...", run: runUpload},
+	{name: "cache", usage: "inspect or maintain the local cache: cache stats|clear|evict|compact|export|import", run: runCache},
+	{name: "quota", usage: "show API requests made today and the remaining daily budget", run: runQuota},
+	{name: "gallery", usage: "write albums as a static HTML gallery", run: runGallery},
+}
+
+var usageErr = errors.New("invalid usage")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if err != usageErr {
+			_, _ = fmt.Fprintln(os.Stderr, "gphoto:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run parse global flags and execute the command.
+func run(args []string, stdout, stderr io.Writer) error {
+	var (
+		flags      gphoto.Config
+		configPath string
+		format     string
+		verbose    bool
+	)
+
+	fs := flag.NewFlagSet("gphoto", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&configPath, "config", os.Getenv(envConfig), "path to YAML or JSON config file")
+	fs.StringVar(&flags.ClientID, "client-id", "", "OAuth client ID")
+	fs.StringVar(&flags.ClientSecret, "client-secret", "", "OAuth client secret")
+	fs.StringVar(&flags.RefreshToken, "refresh-token", "", "OAuth refresh token")
+	fs.StringVar(&flags.DBPath, "db", "", "path to the cache database")
+	fs.StringVar(&format, "format", formatTable, "output format: table, json or csv")
+	fs.StringVar(&flags.LogLevel, "log-level", "", "log level: debug, info, warning or error")
+	fs.BoolVar(&verbose, "v", false, "verbose logging, same as -log-level debug")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "Usage: gphoto [global flags] <command> [command flags] [args]")
+		_, _ = fmt.Fprintln(stderr, "\nCommands:")
+		for _, cmd := range commands {
+			_, _ = fmt.Fprintf(stderr, "  %-10s %s\n", cmd.name, cmd.usage)
+		}
+		_, _ = fmt.Fprintln(stderr, "\nGlobal flags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return usageErr
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return usageErr
+	}
+
+	if verbose {
+		flags.LogLevel = logrus.DebugLevel.String()
+	}
+	logrus.SetOutput(stderr)
+	logrus.SetLevel(logrus.WarnLevel)
+
+	out, err := newOutput(format, stdout)
+	if err != nil {
+		return err
+	}
+	cfg, err := resolveConfig(flags, configPath)
+	if err != nil {
+		return err
+	}
+
+	name := fs.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			a := &app{cfg: cfg, out: out, stderr: stderr}
+			defer a.close()
+			return cmd.run(a, fs.Args()[1:])
+		}
+	}
+	_, _ = fmt.Fprintf(stderr, "unknown command %q\n", name)
+	fs.Usage()
+	return usageErr
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_output_write(t *testing.T) {
+	type album struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+	value := []album{{ID: "1", Title: "Summer, 2019"}}
+	headers := []string{"ID", "TITLE"}
+	rows := [][]string{{"1", "Summer, 2019"}}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: formatTable, want: "ID  TITLE\n1   Summer, 2019\n"},
+		{format: formatCSV, want: "ID,TITLE\n1,\"Summer, 2019\"\n"},
+		{format: formatJSON, want: "[\n  {\n    \"id\": \"1\",\n    \"title\": \"Summer, 2019\"\n  }\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			out, err := newOutput(tt.format, &buf)
+			assert.NoError(t, err)
+			assert.NoError(t, out.write(value, headers, rows))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+
+	_, err := newOutput("xml", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,264 @@
+package gphoto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// Environment variables read by Config.LoadEnv.
+const (
+	EnvClientID         = "GPHOTO_CLIENT_ID"
+	EnvClientSecret     = "GPHOTO_CLIENT_SECRET"
+	EnvRefreshToken     = "GPHOTO_REFRESH_TOKEN"
+	EnvDBPath           = "GPHOTO_DB_PATH"
+	EnvHTTPTimeout      = "GPHOTO_HTTP_TIMEOUT"
+	EnvRetryMaxAttempts = "GPHOTO_RETRY_MAX_ATTEMPTS"
+	EnvRetryMinBackoff  = "GPHOTO_RETRY_MIN_BACKOFF"
+	EnvRetryMaxBackoff  = "GPHOTO_RETRY_MAX_BACKOFF"
+	EnvCachePhotoTTL    = "GPHOTO_CACHE_PHOTO_TTL"
+	EnvCacheAlbumTTL    = "GPHOTO_CACHE_ALBUM_TTL"
+	EnvCacheMaxAlbums   = "GPHOTO_CACHE_MAX_ALBUMS"
+	EnvCacheMaxAge      = "GPHOTO_CACHE_MAX_AGE"
+	EnvCacheMaxBytes    = "GPHOTO_CACHE_MAX_BYTES"
+	EnvCacheEvictEvery  = "GPHOTO_CACHE_EVICT_INTERVAL"
+	EnvRateReadsPerMin  = "GPHOTO_RATE_READS_PER_MINUTE"
+	EnvRateWritesPerMin = "GPHOTO_RATE_WRITES_PER_MINUTE"
+	EnvRateDownloadBPS  = "GPHOTO_RATE_DOWNLOAD_BYTES_PER_SECOND"
+	EnvRateDailyLimit   = "GPHOTO_RATE_DAILY_REQUESTS"
+	EnvLogLevel         = "GPHOTO_LOG_LEVEL"
+)
+
+// Duration is a time.Duration written as a string like "1m30s" in config files.
+type Duration time.Duration
+
+// UnmarshalText parse duration string.
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// MarshalText format duration as a string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config of the Client, see NewClientFromConfig.
+// LogLevel set the global log level, empty keeps it as is.
+type Config struct {
+	ClientID     string      `json:"client_id" yaml:"client_id"`
+	ClientSecret string      `json:"client_secret" yaml:"client_secret"`
+	RefreshToken string      `json:"refresh_token" yaml:"refresh_token"`
+	DBPath       string      `json:"db_path" yaml:"db_path"`
+	HTTPTimeout  Duration    `json:"http_timeout" yaml:"http_timeout"`
+	Retry        RetryPolicy `json:"retry" yaml:"retry"`
+	Cache        CacheConfig `json:"cache" yaml:"cache"`
+	RateLimits   RateLimits  `json:"rate_limits" yaml:"rate_limits"`
+	LogLevel     string      `json:"log_level" yaml:"log_level"`
+}
+
+// CacheConfig hold cache TTLs and limits, zero disables the corresponding cache.
+type CacheConfig struct {
+	// PhotoTTL is how long cached album photos are served without checking their base urls.
+	PhotoTTL Duration `json:"photo_ttl" yaml:"photo_ttl"`
+	// AlbumTTL is how long the album list is kept in memory.
+	AlbumTTL Duration `json:"album_ttl" yaml:"album_ttl"`
+	// Limits bound the cache database size.
+	Limits CacheLimits `json:"limits" yaml:"limits"`
+}
+
+// DefaultConfig return config with default values and no credentials.
+func DefaultConfig() *Config {
+	return &Config{
+		DBPath:      googlePhotoDB,
+		HTTPTimeout: Duration(defaultTimeout),
+		Retry: RetryPolicy{
+			MaxAttempts: 3,
+			MinBackoff:  Duration(500 * time.Millisecond),
+			MaxBackoff:  Duration(10 * time.Second),
+		},
+	}
+}
+
+// LoadConfig read YAML or JSON config file on top of the default config,
+// the format is chosen by file extension.
+func LoadConfig(path string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %s", path, err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, cfg)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	default:
+		return nil, fmt.Errorf("read config %s: unsupported format %q, use .yaml, .yml or .json", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config %s: %s", path, err)
+	}
+	return cfg, nil
+}
+
+// LoadEnv override config values with GPHOTO_* environment variables that are set.
+func (c *Config) LoadEnv() error {
+	strs := map[string]*string{
+		EnvClientID:     &c.ClientID,
+		EnvClientSecret: &c.ClientSecret,
+		EnvRefreshToken: &c.RefreshToken,
+		EnvDBPath:       &c.DBPath,
+		EnvLogLevel:     &c.LogLevel,
+	}
+	for name, field := range strs {
+		if value, ok := os.LookupEnv(name); ok {
+			*field = value
+		}
+	}
+
+	durations := map[string]*Duration{
+		EnvHTTPTimeout:     &c.HTTPTimeout,
+		EnvRetryMinBackoff: &c.Retry.MinBackoff,
+		EnvRetryMaxBackoff: &c.Retry.MaxBackoff,
+		EnvCachePhotoTTL:   &c.Cache.PhotoTTL,
+		EnvCacheAlbumTTL:   &c.Cache.AlbumTTL,
+		EnvCacheMaxAge:     &c.Cache.Limits.MaxAge,
+		EnvCacheEvictEvery: &c.Cache.Limits.EvictInterval,
+	}
+	for name, field := range durations {
+		if value, ok := os.LookupEnv(name); ok {
+			if err := field.UnmarshalText([]byte(value)); err != nil {
+				return fmt.Errorf("env %s: invalid duration %q", name, value)
+			}
+		}
+	}
+
+	ints := map[string]*int{
+		EnvRetryMaxAttempts: &c.Retry.MaxAttempts,
+		EnvCacheMaxAlbums:   &c.Cache.Limits.MaxAlbums,
+		EnvRateReadsPerMin:  &c.RateLimits.ReadsPerMinute,
+		EnvRateWritesPerMin: &c.RateLimits.WritesPerMinute,
+		EnvRateDailyLimit:   &c.RateLimits.DailyRequests,
+	}
+	for name, field := range ints {
+		if value, ok := os.LookupEnv(name); ok {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("env %s: invalid number %q", name, value)
+			}
+			*field = number
+		}
+	}
+
+	int64s := map[string]*int64{
+		EnvCacheMaxBytes:   &c.Cache.Limits.MaxBytes,
+		EnvRateDownloadBPS: &c.RateLimits.DownloadBytesPerSecond,
+	}
+	for name, field := range int64s {
+		if value, ok := os.LookupEnv(name); ok {
+			number, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("env %s: invalid number %q", name, value)
+			}
+			*field = number
+		}
+	}
+	return nil
+}
+
+// Validate check config is complete and consistent, all problems are reported at once.
+func (c *Config) Validate() error {
+	var problems []string
+
+	if c.ClientID == "" {
+		problems = append(problems, "client_id is required")
+	}
+	if c.ClientSecret == "" {
+		problems = append(problems, "client_secret is required")
+	}
+	if c.RefreshToken == "" {
+		problems = append(problems, "refresh_token is required")
+	}
+	if c.DBPath == "" {
+		problems = append(problems, "db_path is required")
+	}
+	if c.HTTPTimeout < 0 {
+		problems = append(problems, "http_timeout must not be negative")
+	}
+	if c.Retry.MaxAttempts < 0 {
+		problems = append(problems, "retry.max_attempts must not be negative")
+	}
+	if c.Retry.MinBackoff < 0 || c.Retry.MaxBackoff < 0 {
+		problems = append(problems, "retry backoff must not be negative")
+	}
+	if c.Retry.MaxBackoff > 0 && c.Retry.MinBackoff > c.Retry.MaxBackoff {
+		problems = append(problems, "retry.min_backoff must not exceed retry.max_backoff")
+	}
+	if c.Cache.PhotoTTL < 0 || c.Cache.AlbumTTL < 0 {
+		problems = append(problems, "cache ttl must not be negative")
+	}
+	if l := c.Cache.Limits; l.MaxAlbums < 0 || l.MaxAge < 0 || l.MaxBytes < 0 || l.EvictInterval < 0 {
+		problems = append(problems, "cache limits must not be negative")
+	}
+	if l := c.RateLimits; l.ReadsPerMinute < 0 || l.WritesPerMinute < 0 || l.DownloadBytesPerSecond < 0 || l.DailyRequests < 0 {
+		problems = append(problems, "rate limits must not be negative")
+	}
+	if r := c.RateLimits.InteractiveReserve; r < 0 || r > 1 {
+		problems = append(problems, "rate_limits.interactive_reserve must be between 0 and 1")
+	}
+	if c.LogLevel != "" {
+		if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+			problems = append(problems, fmt.Sprintf("log_level %q is unknown", c.LogLevel))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid config: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// options convert config into client options.
+func (c *Config) options() []Option {
+	return []Option{
+		WithDBPath(c.DBPath),
+		WithHTTPTimeout(time.Duration(c.HTTPTimeout)),
+		WithRetryPolicy(c.Retry),
+		WithCacheTTL(time.Duration(c.Cache.PhotoTTL), time.Duration(c.Cache.AlbumTTL)),
+		WithCacheLimits(c.Cache.Limits),
+		WithRateLimits(c.RateLimits),
+	}
+}
+
+// NewClientFromConfig validate config and create google photo api Client.
+// The global log level is set when the config has one.
+func NewClientFromConfig(cfg *Config, opts ...Option) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.LogLevel != "" {
+		level, _ := logrus.ParseLevel(cfg.LogLevel)
+		logrus.SetLevel(level)
+	}
+
+	return NewGoogleClient(cfg.ClientID, cfg.ClientSecret, cfg.RefreshToken, append(cfg.options(), opts...)...)
+}
@@ -0,0 +1,118 @@
+package gphoto
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gphoto-config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	want := DefaultConfig()
+	want.ClientID = "CLIENT_ID"
+	want.ClientSecret = "SECRET_ID"
+	want.RefreshToken = "TOKEN"
+	want.HTTPTimeout = Duration(30 * time.Second)
+	want.Retry.MaxAttempts = 5
+	want.Cache.PhotoTTL = Duration(45 * time.Minute)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    *Config
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: "client_id: CLIENT_ID\nclient_secret: SECRET_ID\nrefresh_token: TOKEN\nhttp_timeout: 30s\n" +
+				"retry:\n  max_attempts: 5\ncache:\n  photo_ttl: 45m\n",
+			want: want,
+		},
+		{
+			name: "json",
+			file: "config.json",
+			content: `{"client_id":"CLIENT_ID","client_secret":"SECRET_ID","refresh_token":"TOKEN","http_timeout":"30s",` +
+				`"retry":{"max_attempts":5},"cache":{"photo_ttl":"45m"}}`,
+			want: want,
+		},
+		{
+			name:    "unknown field",
+			file:    "unknown.json",
+			content: `{"client":"CLIENT_ID"}`,
+			wantErr: "parse config",
+		},
+		{
+			name:    "bad duration",
+			file:    "duration.yaml",
+			content: "http_timeout: soon\n",
+			wantErr: "parse config",
+		},
+		{
+			name:    "unsupported format",
+			file:    "config.toml",
+			wantErr: "unsupported format",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			assert.NoError(t, ioutil.WriteFile(path, []byte(tt.content), 0600))
+
+			got, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_LoadEnv(t *testing.T) {
+	env := map[string]string{
+		EnvClientID:         "ENV_ID",
+		EnvHTTPTimeout:      "1m",
+		EnvRetryMaxAttempts: "7",
+		EnvCacheAlbumTTL:    "5m",
+	}
+	for name, value := range env {
+		assert.NoError(t, os.Setenv(name, value))
+		defer os.Unsetenv(name)
+	}
+
+	cfg := DefaultConfig()
+	assert.NoError(t, cfg.LoadEnv())
+	assert.Equal(t, "ENV_ID", cfg.ClientID)
+	assert.Equal(t, Duration(time.Minute), cfg.HTTPTimeout)
+	assert.Equal(t, 7, cfg.Retry.MaxAttempts)
+	assert.Equal(t, Duration(5*time.Minute), cfg.Cache.AlbumTTL)
+
+	assert.NoError(t, os.Setenv(EnvRetryMaxAttempts, "many"))
+	assert.EqualError(t, cfg.LoadEnv(), `env GPHOTO_RETRY_MAX_ATTEMPTS: invalid number "many"`)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ClientID = "CLIENT_ID"
+	cfg.ClientSecret = "SECRET_ID"
+	cfg.RefreshToken = "TOKEN"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.RefreshToken = ""
+	cfg.Retry.MinBackoff = Duration(time.Minute)
+	cfg.LogLevel = "loud"
+	assert.EqualError(t, cfg.Validate(), `invalid config: refresh_token is required; `+
+		`retry.min_backoff must not exceed retry.max_backoff; log_level "loud" is unknown`)
+}
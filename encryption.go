@@ -0,0 +1,271 @@
+package gphoto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	// encryptedMarker prefix encrypted values, plain values are JSON and never start with it.
+	encryptedMarker = 0x01
+	keyIDKey        = "key_id"
+)
+
+var (
+	keyRequiredErr = errors.New("cache is encrypted, key provider is required")
+	unknownKeyErr  = errors.New("unknown encryption key")
+	decryptErr     = errors.New("cache record decryption error")
+	rotateKeyErr   = errors.New("cache key rotation error")
+)
+
+// KeyProvider supply AES keys (16, 24 or 32 bytes) for encryption of cached records.
+type KeyProvider interface {
+	// CurrentKey return the key used to encrypt new records.
+	CurrentKey() (id string, key []byte, err error)
+	// Key return the key by its id, keys of existing records must stay available until rotation.
+	Key(id string) ([]byte, error)
+}
+
+// KeyRing is a KeyProvider holding keys in memory.
+type KeyRing struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+// CurrentKey return key with Current id.
+func (k *KeyRing) CurrentKey() (string, []byte, error) {
+	key, err := k.Key(k.Current)
+	return k.Current, key, err
+}
+
+// Key return key by id.
+func (k *KeyRing) Key(id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, unknownKeyErr
+	}
+	return key, nil
+}
+
+// keyRotator is implemented by repositories able to re-encrypt their records.
+type keyRotator interface {
+	rotateKey() (int, error)
+}
+
+// RotateCacheKey re-encrypt cached records with the current key of the KeyProvider
+// and return the number of rewritten records.
+func (c *Client) RotateCacheKey() (int, error) {
+	rotator, ok := c.repo.(keyRotator)
+	if !ok {
+		return 0, cacheNotSupportedErr
+	}
+
+	count, err := rotator.rotateKey()
+	if err != nil {
+		logrus.WithError(err).Errorln(rotateKeyErr)
+		return count, rotateKeyErr
+	}
+	return count, nil
+}
+
+// NewEncryptedBoltRepository make BoltRepository encrypting records with AES-GCM.
+// Records written with another key or in plain text are re-encrypted on open.
+func NewEncryptedBoltRepository(DB *bbolt.DB, keys KeyProvider) (*BoltRepository, error) {
+	return newBoltRepository(DB, keys, "")
+}
+
+// marshal encode value stored in the bolt buckets, it is encrypted if the repository has keys.
+func (r *BoltRepository) marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || r.keys == nil {
+		return data, err
+	}
+
+	id, key, err := r.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	return encrypt(id, key, data)
+}
+
+// unmarshal decode value stored in the bolt buckets.
+func (r *BoltRepository) unmarshal(data []byte, v interface{}) error {
+	data, err := r.decrypt(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// decrypt return plain value, plain values are returned as is.
+func (r *BoltRepository) decrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != encryptedMarker {
+		return data, nil
+	}
+	if r.keys == nil {
+		return nil, keyRequiredErr
+	}
+
+	id, sealed, ok := splitEncrypted(data)
+	if !ok {
+		return nil, decryptErr
+	}
+	key, err := r.keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, decryptErr
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, decryptErr
+	}
+	return plain, nil
+}
+
+// rotateKey re-encrypt records of photo, album and sync buckets not encrypted with the current key.
+func (r *BoltRepository) rotateKey() (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	current, key, err := r.keys.CurrentKey()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = r.update(func(tx *bbolt.Tx) error {
+		var buckets []*bbolt.Bucket
+		for _, name := range []string{albumBucket, syncBucket} {
+			if b := r.buckets(tx).Bucket([]byte(name)); b != nil {
+				buckets = append(buckets, b)
+			}
+		}
+		if pBucket := r.buckets(tx).Bucket([]byte(photoBucket)); pBucket != nil {
+			err := pBucket.ForEach(func(k, v []byte) error {
+				if v == nil {
+					buckets = append(buckets, pBucket.Bucket(k))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, b := range buckets {
+			n, err := r.reencryptBucket(b, current, key)
+			if err != nil {
+				return err
+			}
+			count += n
+		}
+
+		meta, err := r.buckets(tx).CreateBucketIfNotExists([]byte(metaBucket))
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte(keyIDKey), []byte(current))
+	})
+	if err == nil {
+		logrus.WithField("count", count).Infoln("cache records re-encrypted")
+	}
+	return count, err
+}
+
+// reencryptBucket rewrite values of the bucket not encrypted with the current key.
+func (r *BoltRepository) reencryptBucket(b *bbolt.Bucket, current string, key []byte) (int, error) {
+	var keys [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return nil
+		}
+		if id, _, ok := splitEncrypted(v); !ok || id != current {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, k := range keys {
+		plain, err := r.decrypt(b.Get(k))
+		if err != nil {
+			return 0, err
+		}
+		sealed, err := encrypt(current, key, plain)
+		if err != nil {
+			return 0, err
+		}
+		if err = b.Put(k, sealed); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+// storedKeyID read id of the key all records are encrypted with.
+func (r *BoltRepository) storedKeyID() (string, error) {
+	var id string
+	err := r.view(func(tx *bbolt.Tx) error {
+		if meta := r.buckets(tx).Bucket([]byte(metaBucket)); meta != nil {
+			id = string(meta.Get([]byte(keyIDKey)))
+		}
+		return nil
+	})
+	return id, err
+}
+
+// encrypt seal data as marker, key id length, key id, nonce and ciphertext.
+func encrypt(id string, key, data []byte) ([]byte, error) {
+	if len(id) > 255 {
+		return nil, errors.New("key id is too long")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(2 + len(id) + len(nonce) + len(data) + gcm.Overhead())
+	buf.WriteByte(encryptedMarker)
+	buf.WriteByte(byte(len(id)))
+	buf.WriteString(id)
+	buf.Write(nonce)
+	return gcm.Seal(buf.Bytes(), nonce, data, nil), nil
+}
+
+// splitEncrypted return key id and sealed part of the encrypted value.
+func splitEncrypted(data []byte) (string, []byte, bool) {
+	if len(data) < 2 || data[0] != encryptedMarker || len(data) < 2+int(data[1]) {
+		return "", nil, false
+	}
+	n := int(data[1])
+	return string(data[2 : 2+n]), data[2+n:], true
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
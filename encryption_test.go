@@ -0,0 +1,103 @@
+package gphoto
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func testKeyRing() *KeyRing {
+	return &KeyRing{
+		Current: "k1",
+		Keys: map[string][]byte{
+			"k1": bytes.Repeat([]byte{1}, 32),
+			"k2": bytes.Repeat([]byte{2}, 32),
+		},
+	}
+}
+
+// rawValues collect stored values of the album and photo buckets.
+func rawValues(t *testing.T, db *bbolt.DB) [][]byte {
+	var values [][]byte
+	err := db.View(func(tx *bbolt.Tx) error {
+		_ = tx.Bucket([]byte(albumBucket)).ForEach(func(k, v []byte) error {
+			values = append(values, append([]byte(nil), v...))
+			return nil
+		})
+		pBucket := tx.Bucket([]byte(photoBucket))
+		return pBucket.ForEach(func(album, _ []byte) error {
+			return pBucket.Bucket(album).ForEach(func(k, v []byte) error {
+				values = append(values, append([]byte(nil), v...))
+				return nil
+			})
+		})
+	})
+	assert.NoError(t, err)
+	return values
+}
+
+func TestNewEncryptedBoltRepository(t *testing.T) {
+	db, dir := newTempBolt(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	plain, err := NewBoltRepository(db)
+	assert.NoError(t, err)
+	assert.NoError(t, plain.SavePhotos("album", []*GooglePhoto{{ID: "secret-photo"}}))
+	assert.NoError(t, plain.SaveAlbums([]*GoogleAlbum{{ID: "album", Title: "secret-title"}}))
+
+	keys := testKeyRing()
+	repo, err := NewEncryptedBoltRepository(db, keys)
+	assert.NoError(t, err)
+
+	t.Run("plain records are encrypted on open", func(t *testing.T) {
+		for _, v := range rawValues(t, db) {
+			id, _, ok := splitEncrypted(v)
+			assert.True(t, ok)
+			assert.Equal(t, "k1", id)
+			assert.False(t, bytes.Contains(v, []byte("secret")))
+		}
+
+		photos, err := repo.ListPhotos("album")
+		assert.NoError(t, err)
+		assert.Equal(t, []*GooglePhoto{{ID: "secret-photo"}}, photos)
+	})
+
+	t.Run("key is required", func(t *testing.T) {
+		_, err := NewBoltRepository(db)
+		assert.Equal(t, keyRequiredErr, err)
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		keys.Current = "k2"
+		c := &Client{repo: repo}
+		count, err := c.RotateCacheKey()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		delete(keys.Keys, "k1")
+		album, err := repo.GetAlbum("album")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret-title", album.Title)
+
+		count, err = c.RotateCacheKey()
+		assert.NoError(t, err)
+		assert.Zero(t, count, "records with the current key are kept")
+	})
+
+	t.Run("tampered record", func(t *testing.T) {
+		err := db.Update(func(tx *bbolt.Tx) error {
+			b := tx.Bucket([]byte(albumBucket))
+			v := append([]byte(nil), b.Get([]byte("album"))...)
+			v[len(v)-1] ^= 0xff
+			return b.Put([]byte("album"), v)
+		})
+		assert.NoError(t, err)
+
+		_, err = repo.GetAlbum("album")
+		assert.Equal(t, decryptErr, err)
+	})
+}
@@ -0,0 +1,164 @@
+package gphoto
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Album positions of AlbumPosition.
+const (
+	PositionFirstInAlbum        = "FIRST_IN_ALBUM"
+	PositionLastInAlbum         = "LAST_IN_ALBUM"
+	PositionAfterMediaItem      = "AFTER_MEDIA_ITEM"
+	PositionAfterEnrichmentItem = "AFTER_ENRICHMENT_ITEM"
+)
+
+var (
+	addEnrichmentErr     = errors.New("add enrichment error")
+	invalidEnrichmentErr = errors.New("exactly one of text, location or map enrichment must be set")
+	invalidPositionErr   = errors.New("invalid album position")
+)
+
+// NewEnrichmentItem is an enrichment to add to the album, exactly one field must be set.
+type NewEnrichmentItem struct {
+	TextEnrichment     *TextEnrichment     `json:"textEnrichment,omitempty"`
+	LocationEnrichment *LocationEnrichment `json:"locationEnrichment,omitempty"`
+	MapEnrichment      *MapEnrichment      `json:"mapEnrichment,omitempty"`
+}
+
+// TextEnrichment is a caption between album items.
+type TextEnrichment struct {
+	Text string `json:"text"`
+}
+
+// LocationEnrichment is a single location pin.
+type LocationEnrichment struct {
+	Location Location `json:"location"`
+}
+
+// MapEnrichment is a map showing a route from origin to destination.
+type MapEnrichment struct {
+	Origin      Location `json:"origin"`
+	Destination Location `json:"destination"`
+}
+
+// Location is a named point on the map.
+type Location struct {
+	LocationName string `json:"locationName"`
+	Latlng       LatLng `json:"latlng"`
+}
+
+// LatLng is a pair of latitude and longitude in degrees.
+type LatLng struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// AlbumPosition specify where in the album an item is placed.
+type AlbumPosition struct {
+	Position                 string `json:"position"`
+	RelativeMediaItemID      string `json:"relativeMediaItemId,omitempty"`
+	RelativeEnrichmentItemID string `json:"relativeEnrichmentItemId,omitempty"`
+}
+
+type addEnrichmentRequest struct {
+	NewEnrichmentItem NewEnrichmentItem `json:"newEnrichmentItem"`
+	AlbumPosition     AlbumPosition     `json:"albumPosition"`
+}
+
+// NewTextEnrichment make text enrichment item.
+func NewTextEnrichment(text string) NewEnrichmentItem {
+	return NewEnrichmentItem{TextEnrichment: &TextEnrichment{Text: text}}
+}
+
+// NewLocationEnrichment make location enrichment item.
+func NewLocationEnrichment(location Location) NewEnrichmentItem {
+	return NewEnrichmentItem{LocationEnrichment: &LocationEnrichment{Location: location}}
+}
+
+// NewMapEnrichment make map enrichment item.
+func NewMapEnrichment(origin, destination Location) NewEnrichmentItem {
+	return NewEnrichmentItem{MapEnrichment: &MapEnrichment{Origin: origin, Destination: destination}}
+}
+
+// FirstInAlbum place an item at the beginning of the album.
+func FirstInAlbum() AlbumPosition {
+	return AlbumPosition{Position: PositionFirstInAlbum}
+}
+
+// LastInAlbum place an item at the end of the album.
+func LastInAlbum() AlbumPosition {
+	return AlbumPosition{Position: PositionLastInAlbum}
+}
+
+// AfterMediaItem place an item right after the media item.
+func AfterMediaItem(mediaID string) AlbumPosition {
+	return AlbumPosition{Position: PositionAfterMediaItem, RelativeMediaItemID: mediaID}
+}
+
+// AfterEnrichmentItem place an item right after the enrichment item.
+func AfterEnrichmentItem(enrichmentID string) AlbumPosition {
+	return AlbumPosition{Position: PositionAfterEnrichmentItem, RelativeEnrichmentItemID: enrichmentID}
+}
+
+// validate check exactly one enrichment is set.
+func (e NewEnrichmentItem) validate() error {
+	var count int
+	if e.TextEnrichment != nil {
+		count++
+	}
+	if e.LocationEnrichment != nil {
+		count++
+	}
+	if e.MapEnrichment != nil {
+		count++
+	}
+	if count != 1 {
+		return invalidEnrichmentErr
+	}
+	return nil
+}
+
+// validate check the relative item is set for positions that need it.
+func (p AlbumPosition) validate() error {
+	switch p.Position {
+	case PositionFirstInAlbum, PositionLastInAlbum:
+		return nil
+	case PositionAfterMediaItem:
+		if p.RelativeMediaItemID != "" {
+			return nil
+		}
+	case PositionAfterEnrichmentItem:
+		if p.RelativeEnrichmentItemID != "" {
+			return nil
+		}
+	}
+	return invalidPositionErr
+}
+
+// AddEnrichment add text, location or map enrichment to the album and return the enrichment item ID.
+func (c *Client) AddEnrichment(albumID string, enrichment NewEnrichmentItem, position AlbumPosition) (string, error) {
+	if err := enrichment.validate(); err != nil {
+		return "", err
+	}
+	if err := position.validate(); err != nil {
+		return "", err
+	}
+
+	var enrichmentID string
+	err := c.withAuth(func(accessToken string) error {
+		var err error
+		enrichmentID, err = c.api.addEnrichment(accessToken, albumID, enrichment, position)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return "", err
+	} else if err != nil {
+		logrus.WithError(err).WithField("album", albumID).Errorln(addEnrichmentErr)
+		return "", addEnrichmentErr
+	}
+
+	logrus.WithFields(logrus.Fields{"album": albumID, "enrichment": enrichmentID}).Debugln("enrichment added")
+	return enrichmentID, nil
+}
@@ -0,0 +1,75 @@
+package gphoto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_AddEnrichment(t *testing.T) {
+	rome := Location{LocationName: "Rome", Latlng: LatLng{Latitude: 41.9, Longitude: 12.5}}
+	paris := Location{LocationName: "Paris", Latlng: LatLng{Latitude: 48.85, Longitude: 2.35}}
+
+	tests := []struct {
+		name       string
+		enrichment NewEnrichmentItem
+		position   AlbumPosition
+		setup      func(api *MockedApi)
+		want       string
+		wantErr    error
+	}{
+		{
+			name:       "text first in album",
+			enrichment: NewTextEnrichment("Day one"),
+			position:   FirstInAlbum(),
+			setup: func(api *MockedApi) {
+				api.On("addEnrichment", "ACCESS_TOKEN", "album", NewTextEnrichment("Day one"), FirstInAlbum()).Return("text-id", nil).Once()
+			},
+			want: "text-id",
+		},
+		{
+			name:       "map after media item",
+			enrichment: NewMapEnrichment(rome, paris),
+			position:   AfterMediaItem("photo"),
+			setup: func(api *MockedApi) {
+				api.On("addEnrichment", "ACCESS_TOKEN", "album", NewMapEnrichment(rome, paris), AfterMediaItem("photo")).Return("map-id", nil).Once()
+			},
+			want: "map-id",
+		},
+		{
+			name:       "api fail",
+			enrichment: NewLocationEnrichment(rome),
+			position:   LastInAlbum(),
+			setup: func(api *MockedApi) {
+				api.On("addEnrichment", "ACCESS_TOKEN", "album", NewLocationEnrichment(rome), LastInAlbum()).Return("", someErr).Once()
+			},
+			wantErr: addEnrichmentErr,
+		},
+		{
+			name:       "several enrichments",
+			enrichment: NewEnrichmentItem{TextEnrichment: &TextEnrichment{}, LocationEnrichment: &LocationEnrichment{}},
+			position:   LastInAlbum(),
+			setup:      func(api *MockedApi) {},
+			wantErr:    invalidEnrichmentErr,
+		},
+		{
+			name:       "position without relative item",
+			enrichment: NewTextEnrichment("caption"),
+			position:   AfterEnrichmentItem(""),
+			setup:      func(api *MockedApi) {},
+			wantErr:    invalidPositionErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := new(MockedApi)
+			tt.setup(api)
+			c := &Client{accessToken: "ACCESS_TOKEN", api: api}
+
+			got, err := c.AddEnrichment("album", tt.enrichment, tt.position)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+			api.AssertExpectations(t)
+		})
+	}
+}
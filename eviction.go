@@ -0,0 +1,282 @@
+package gphoto
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	compactSuffix = ".compact"
+	// compactTxSize limit bytes copied in a single transaction of the compaction.
+	compactTxSize = 64 << 20
+)
+
+var (
+	evictErr   = errors.New("cache eviction error")
+	compactErr = errors.New("cache compaction error")
+	// compactReopenErr leave the repository closed, the Client must be created again.
+	compactReopenErr = errors.New("cache database is not reopened after compaction")
+)
+
+// replaceFile and openCompacted are replaced in tests to fail the last compaction steps.
+var (
+	replaceFile   = os.Rename
+	openCompacted = initDB
+)
+
+// CacheLimits bound photos kept in the cache, least recently used albums are evicted first.
+// Zero disables the corresponding limit.
+type CacheLimits struct {
+	// MaxAlbums is the number of albums whose photos are kept.
+	MaxAlbums int `json:"max_albums" yaml:"max_albums"`
+	// MaxAge is how long photos of an album not requested again are kept.
+	MaxAge Duration `json:"max_age" yaml:"max_age"`
+	// MaxBytes is the size of cached photo records.
+	MaxBytes int64 `json:"max_bytes" yaml:"max_bytes"`
+	// EvictInterval is how often limits are enforced in background.
+	EvictInterval Duration `json:"evict_interval" yaml:"evict_interval"`
+}
+
+// enabled report whether any limit is set.
+func (l CacheLimits) enabled() bool {
+	return l.MaxAlbums > 0 || l.MaxAge > 0 || l.MaxBytes > 0
+}
+
+// cacheEvictor is implemented by repositories able to drop least recently used albums.
+type cacheEvictor interface {
+	evict(limits CacheLimits, now time.Time) (int, error)
+}
+
+// cacheCompactor is implemented by repositories able to give freed space back to the filesystem.
+type cacheCompactor interface {
+	compact() error
+}
+
+// EvictCache drop photos of albums exceeding the cache limits and return the number of evicted albums.
+func (c *Client) EvictCache() (int, error) {
+	evictor, ok := c.repo.(cacheEvictor)
+	if !ok {
+		return 0, cacheNotSupportedErr
+	}
+
+	evicted, err := evictor.evict(c.limits, time.Now())
+	if err != nil {
+		logrus.WithError(err).Errorln(evictErr)
+		return evicted, evictErr
+	}
+	return evicted, nil
+}
+
+// CompactCache rewrite the cache database file without free pages.
+func (c *Client) CompactCache() error {
+	compactor, ok := c.repo.(cacheCompactor)
+	if !ok {
+		return cacheNotSupportedErr
+	}
+
+	if err := compactor.compact(); err == cacheNotSupportedErr || err == compactReopenErr {
+		return err
+	} else if err != nil {
+		logrus.WithError(err).Errorln(compactErr)
+		return compactErr
+	}
+	return nil
+}
+
+// startEviction enforce cache limits every EvictInterval until Close.
+func (c *Client) startEviction() {
+	if !c.limits.enabled() || c.limits.EvictInterval <= 0 {
+		return
+	}
+	if _, ok := c.repo.(cacheEvictor); !ok {
+		logrus.Warnln(cacheNotSupportedErr)
+		return
+	}
+
+	c.evictStop = make(chan struct{})
+	c.evictDone = make(chan struct{})
+	go func() {
+		defer close(c.evictDone)
+
+		ticker := time.NewTicker(time.Duration(c.limits.EvictInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.evictStop:
+				return
+			case <-ticker.C:
+				if evicted, err := c.EvictCache(); err == nil && evicted > 0 {
+					logrus.WithField("count", evicted).Debugln("albums evicted from cache")
+				}
+			}
+		}
+	}()
+}
+
+// stopEviction stop background eviction and wait for it to finish.
+func (c *Client) stopEviction() {
+	if c.evictStop == nil {
+		return
+	}
+	close(c.evictStop)
+	<-c.evictDone
+	c.evictStop = nil
+}
+
+// albumUsage describe cached photos of the album.
+type albumUsage struct {
+	album    string
+	accessed time.Time
+	bytes    int64
+}
+
+// evict delete photo buckets of albums exceeding the limits, least recently used first.
+func (r *BoltRepository) evict(limits CacheLimits, now time.Time) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var evicted int
+
+	err := r.update(func(tx *bbolt.Tx) error {
+		pBucket := r.buckets(tx).Bucket([]byte(photoBucket))
+		access, err := r.buckets(tx).CreateBucketIfNotExists([]byte(accessBucket))
+		if err != nil {
+			return err
+		}
+
+		var (
+			usage []albumUsage
+			total int64
+		)
+		err = pBucket.ForEach(func(k, v []byte) error {
+			albumBucket := pBucket.Bucket(k)
+			if albumBucket == nil {
+				return nil
+			}
+			u := albumUsage{album: string(k), accessed: parseAccessTime(access.Get(k))}
+			err := albumBucket.ForEach(func(k, v []byte) error {
+				u.bytes += int64(len(k) + len(v))
+				return nil
+			})
+			total += u.bytes
+			usage = append(usage, u)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		// the most recently used first, so victims are taken from the tail.
+		sort.Slice(usage, func(i, j int) bool {
+			return usage[i].accessed.After(usage[j].accessed)
+		})
+		for i := len(usage) - 1; i >= 0; i-- {
+			u := usage[i]
+			expired := limits.MaxAge > 0 && now.Sub(u.accessed) > time.Duration(limits.MaxAge)
+			tooMany := limits.MaxAlbums > 0 && i >= limits.MaxAlbums
+			tooBig := limits.MaxBytes > 0 && total > limits.MaxBytes
+			if !expired && !tooMany && !tooBig {
+				continue
+			}
+
+			if err := pBucket.DeleteBucket([]byte(u.album)); err != nil {
+				return err
+			}
+			if err := access.Delete([]byte(u.album)); err != nil {
+				return err
+			}
+			total -= u.bytes
+			evicted++
+			logrus.WithFields(logrus.Fields{"album": u.album, "bytes": u.bytes}).Debugln("evict album photo")
+		}
+		return nil
+	})
+	return evicted, err
+}
+
+// compact copy the database into a new file replacing the current one.
+// Databases shared by Manager accounts are not compacted, other accounts keep using the file.
+func (r *BoltRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.account != "" {
+		return cacheNotSupportedErr
+	}
+
+	path := r.DB.Path()
+	before := fileSize(path)
+
+	tmp := path + compactSuffix
+	_ = os.Remove(tmp)
+	dst, err := bbolt.Open(tmp, 0600, nil)
+	if err != nil {
+		return err
+	}
+	if err = bbolt.Compact(dst, r.DB, compactTxSize); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	// the open handle keep reading the replaced file until it is closed,
+	// so a failed rename leaves the repository as it was.
+	if err = replaceFile(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = r.DB.Close(); err != nil {
+		logrus.WithError(err).Warnln("replaced cache database is not closed")
+	}
+	// on failure the closed handle is kept, so the repository calls fail instead of panicking.
+	db, err := openCompacted(path)
+	if err != nil {
+		logrus.WithError(err).WithField("path", path).Errorln(compactReopenErr)
+		return compactReopenErr
+	}
+	r.DB = db
+
+	logrus.WithFields(logrus.Fields{"before": before, "after": fileSize(path)}).Infoln("cache compacted")
+	return nil
+}
+
+// touchAlbum record album access time.
+func touchAlbum(b bucketSet, album string) error {
+	access, err := b.CreateBucketIfNotExists([]byte(accessBucket))
+	if err != nil {
+		return err
+	}
+	return access.Put([]byte(album), accessTime(time.Now()))
+}
+
+func accessTime(t time.Time) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(t.UnixNano()))
+	return buf
+}
+
+// parseAccessTime decode album access time, albums without one are the least recently used.
+func parseAccessTime(v []byte) time.Time {
+	if len(v) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(v)))
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,177 @@
+package gphoto
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+// newEvictionRepo create bolt repository with albums a, b and c used in that order.
+func newEvictionRepo(t *testing.T) (*BoltRepository, string) {
+	t.Helper()
+
+	db, dir := newTempBolt(t)
+	repo, err := NewBoltRepository(db)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	for _, album := range []string{"a", "b", "c"} {
+		assert.NoError(t, repo.SavePhotos(album, []*GooglePhoto{{ID: album}}))
+		time.Sleep(time.Millisecond)
+	}
+	return repo, dir
+}
+
+func TestBoltRepository_evict(t *testing.T) {
+	cached := func(repo *BoltRepository) []string {
+		var albums []string
+		for _, album := range []string{"a", "b", "c"} {
+			if _, err := repo.ListPhotos(album); err == nil {
+				albums = append(albums, album)
+			}
+		}
+		return albums
+	}
+
+	tests := []struct {
+		name    string
+		limits  func(now time.Time, repo *BoltRepository) CacheLimits
+		evicted int
+		want    []string
+	}{
+		{
+			name: "max albums",
+			limits: func(time.Time, *BoltRepository) CacheLimits {
+				return CacheLimits{MaxAlbums: 2}
+			},
+			evicted: 1,
+			want:    []string{"b", "c"},
+		},
+		{
+			name: "max age",
+			limits: func(now time.Time, repo *BoltRepository) CacheLimits {
+				var accessed time.Time
+				_ = repo.DB.View(func(tx *bbolt.Tx) error {
+					accessed = parseAccessTime(tx.Bucket([]byte(accessBucket)).Get([]byte("b")))
+					return nil
+				})
+				return CacheLimits{MaxAge: Duration(now.Sub(accessed) - time.Nanosecond)}
+			},
+			evicted: 2,
+			want:    []string{"c"},
+		},
+		{
+			name: "max bytes",
+			limits: func(time.Time, *BoltRepository) CacheLimits {
+				return CacheLimits{MaxBytes: 1}
+			},
+			evicted: 3,
+		},
+		{
+			name: "no limits",
+			limits: func(time.Time, *BoltRepository) CacheLimits {
+				return CacheLimits{}
+			},
+			want: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, dir := newEvictionRepo(t)
+			defer os.RemoveAll(dir)
+			defer repo.Close()
+
+			now := time.Now()
+			evicted, err := repo.evict(tt.limits(now, repo), now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.evicted, evicted)
+			assert.Equal(t, tt.want, cached(repo))
+		})
+	}
+}
+
+func TestBoltRepository_compact(t *testing.T) {
+	repo, dir := newEvictionRepo(t)
+	defer os.RemoveAll(dir)
+	defer repo.Close()
+
+	photos := make([]*GooglePhoto, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		photos = append(photos, &GooglePhoto{ID: fmt.Sprint(i), Description: strings.Repeat("x", 100)})
+	}
+	assert.NoError(t, repo.SavePhotos("big", photos))
+	assert.NoError(t, repo.TruncateAlbum("big"))
+
+	before := fileSize(repo.DB.Path())
+	assert.NoError(t, repo.compact())
+	assert.True(t, fileSize(repo.DB.Path()) < before)
+
+	got, err := repo.ListPhotos("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []*GooglePhoto{{ID: "a"}}, got)
+	assert.NoError(t, repo.SavePhotos("a", []*GooglePhoto{{ID: "next"}}))
+	got, err = repo.ListPhotos("a")
+	assert.NoError(t, err)
+	assert.Len(t, got, 2, "bucket sequence is kept")
+}
+
+func TestBoltRepository_compactRenameFailed(t *testing.T) {
+	repo, dir := newEvictionRepo(t)
+	defer os.RemoveAll(dir)
+	defer repo.Close()
+
+	renameErr := errors.New("rename")
+	replaceFile = func(string, string) error { return renameErr }
+	defer func() { replaceFile = os.Rename }()
+
+	path := repo.DB.Path()
+	assert.Equal(t, renameErr, repo.compact())
+	assert.Equal(t, path, repo.DB.Path())
+	_, err := os.Stat(path + compactSuffix)
+	assert.True(t, os.IsNotExist(err), "compacted file is removed")
+
+	got, err := repo.ListPhotos("a")
+	assert.NoError(t, err, "repository is still usable")
+	assert.Equal(t, []*GooglePhoto{{ID: "a"}}, got)
+	assert.NoError(t, repo.SavePhotos("a", []*GooglePhoto{{ID: "next"}}))
+}
+
+func TestBoltRepository_compactReopenFailed(t *testing.T) {
+	repo, dir := newEvictionRepo(t)
+	defer os.RemoveAll(dir)
+
+	openCompacted = func(string) (*bbolt.DB, error) { return nil, errors.New("open") }
+	defer func() { openCompacted = initDB }()
+
+	c := &Client{repo: repo}
+	assert.Equal(t, compactReopenErr, c.CompactCache())
+	assert.NotNil(t, repo.DB)
+
+	_, err := repo.ListPhotos("a")
+	assert.Equal(t, bbolt.ErrDatabaseNotOpen, err, "repository is clearly unusable")
+	assert.Error(t, repo.SavePhotos("a", []*GooglePhoto{{ID: "next"}}))
+}
+
+func TestClient_backgroundEviction(t *testing.T) {
+	repo, dir := newEvictionRepo(t)
+	defer os.RemoveAll(dir)
+
+	c := &Client{repo: repo, limits: CacheLimits{MaxAlbums: 1, EvictInterval: Duration(time.Millisecond)}}
+	c.startEviction()
+	time.Sleep(50 * time.Millisecond)
+	c.stopEviction()
+	assert.Nil(t, c.evictStop)
+
+	_, err := repo.ListPhotos("a")
+	assert.Equal(t, ErrAlbumNotExists, err)
+	_, err = repo.ListPhotos("c")
+	assert.NoError(t, err)
+	assert.NoError(t, c.Close())
+}
@@ -0,0 +1,69 @@
+package gphoto
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+)
+
+// ExpvarMetrics is a Metrics publishing counters with the expvar package, they are served
+// by the /debug/vars handler. API latency is published as a count and a sum of seconds
+// per endpoint and status, so the mean can be derived.
+type ExpvarMetrics struct {
+	root       *expvar.Map
+	requests   *expvar.Map
+	latency    *expvar.Map
+	cache      *expvar.Map
+	refresh    *expvar.Map
+	downloaded *expvar.Int
+}
+
+// NewExpvarMetrics publish metrics as an expvar map with the name.
+// Like expvar.Publish it panics if the name is already in use.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := &ExpvarMetrics{
+		root:       expvar.NewMap(name),
+		requests:   new(expvar.Map).Init(),
+		latency:    new(expvar.Map).Init(),
+		cache:      new(expvar.Map).Init(),
+		refresh:    new(expvar.Map).Init(),
+		downloaded: new(expvar.Int),
+	}
+	m.root.Set("api_requests", m.requests)
+	m.root.Set("api_duration_seconds", m.latency)
+	m.root.Set("cache_lookups", m.cache)
+	m.root.Set("token_refreshes", m.refresh)
+	m.root.Set("downloaded_bytes", m.downloaded)
+	return m
+}
+
+// Map return the published map.
+func (m *ExpvarMetrics) Map() *expvar.Map {
+	return m.root
+}
+
+// ObserveAPICall implement Metrics.
+func (m *ExpvarMetrics) ObserveAPICall(endpoint string, status int, duration time.Duration) {
+	key := endpoint + " " + strconv.Itoa(status)
+	m.requests.Add(key, 1)
+	m.latency.AddFloat(key, duration.Seconds())
+}
+
+// ObserveCacheLookup implement Metrics.
+func (m *ExpvarMetrics) ObserveCacheLookup(cache, result string) {
+	m.cache.Add(cache+" "+result, 1)
+}
+
+// ObserveTokenRefresh implement Metrics.
+func (m *ExpvarMetrics) ObserveTokenRefresh(success bool) {
+	if success {
+		m.refresh.Add("success", 1)
+	} else {
+		m.refresh.Add("failure", 1)
+	}
+}
+
+// ObserveDownload implement Metrics.
+func (m *ExpvarMetrics) ObserveDownload(bytes int64) {
+	m.downloaded.Add(bytes)
+}
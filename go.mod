@@ -5,5 +5,6 @@ go 1.12
 require (
 	github.com/sirupsen/logrus v1.4.2
 	github.com/stretchr/testify v1.2.2
-	go.etcd.io/bbolt v1.3.3
+	go.etcd.io/bbolt v1.3.6
+	gopkg.in/yaml.v2 v2.2.2
 )
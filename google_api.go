@@ -1,10 +1,14 @@
 package gphoto
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -15,10 +19,20 @@ import (
 )
 
 type googleApi struct {
-	client         *http.Client
+	client *http.Client
+	// downloader read media content, its bodies are not bounded by the API request timeout.
+	downloader     *http.Client
 	getAlbumsURL   string
+	sharedAlbumURL string
 	searchPhotoURL string
+	mediaItemsURL  string
+	uploadsURL     string
 	getTokenURL    string
+	retry          RetryPolicy
+	metrics        Metrics
+	tracer         Tracer
+	ctx            context.Context
+	limiter        *rateLimiter
 }
 
 type refreshResponse struct {
@@ -28,22 +42,101 @@ type refreshResponse struct {
 	TokenType   string `json:"token_type"`
 }
 
-const defaultLimit = 100
+type searchRequest struct {
+	AlbumID   string         `json:"albumId,omitempty"`
+	PageSize  int            `json:"pageSize"`
+	PageToken string         `json:"pageToken,omitempty"`
+	Filters   *searchFilters `json:"filters,omitempty"`
+}
+
+type searchFilters struct {
+	DateFilter *struct {
+		Ranges []searchDateRange `json:"ranges"`
+	} `json:"dateFilter,omitempty"`
+	ContentFilter *struct {
+		IncludedContentCategories []string `json:"includedContentCategories"`
+	} `json:"contentFilter,omitempty"`
+	MediaTypeFilter *struct {
+		MediaTypes []string `json:"mediaTypes"`
+	} `json:"mediaTypeFilter,omitempty"`
+	FeatureFilter *struct {
+		IncludedFeatures []string `json:"includedFeatures"`
+	} `json:"featureFilter,omitempty"`
+}
+
+type searchDateRange struct {
+	StartDate searchDate `json:"startDate"`
+	EndDate   searchDate `json:"endDate"`
+}
+
+type searchDate struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day"`
+}
+
+type batchCreateRequest struct {
+	AlbumID       string         `json:"albumId,omitempty"`
+	NewMediaItems []newMediaItem `json:"newMediaItems"`
+}
+
+type newMediaItem struct {
+	Description     string `json:"description,omitempty"`
+	SimpleMediaItem struct {
+		FileName    string `json:"fileName"`
+		UploadToken string `json:"uploadToken"`
+	} `json:"simpleMediaItem"`
+}
+
+type batchCreateResponse struct {
+	NewMediaItemResults []struct {
+		UploadToken string `json:"uploadToken"`
+		Status      struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"status"`
+		MediaItem *GooglePhoto `json:"mediaItem"`
+	} `json:"newMediaItemResults"`
+}
+
+const (
+	defaultLibraryURL = "https://photoslibrary.googleapis.com"
+	defaultTokenURL   = "https://accounts.google.com/o/oauth2/token"
+	defaultLimit      = 100
+	defaultAlbumLimit = 50
+	defaultTimeout    = time.Second * 10
+)
 
 var (
 	unauthorizedErr = errors.New("unauthorized")
 	badStatusErr    = errors.New("bad status")
+	rangeErr        = errors.New("range not satisfiable")
+	notFoundErr     = errors.New("not found")
 )
 
 // NewGoogleApi represent client for low-level requests to Google Photo Api.
 func NewGoogleApi() *googleApi {
+	return newGoogleApi(defaultOptions())
+}
+
+func newGoogleApi(opts *options) *googleApi {
 	return &googleApi{
 		client: &http.Client{
-			Timeout: time.Second * 10,
+			Timeout:   opts.httpTimeout,
+			Transport: opts.transport,
 		},
-		getAlbumsURL:   "https://photoslibrary.googleapis.com/v1/albums",
-		searchPhotoURL: "https://photoslibrary.googleapis.com/v1/mediaItems:search",
-		getTokenURL:    "https://accounts.google.com/o/oauth2/token",
+		downloader: &http.Client{
+			Transport: downloadTransport(opts),
+		},
+		getAlbumsURL:   opts.libraryURL + "/v1/albums",
+		sharedAlbumURL: opts.libraryURL + "/v1/sharedAlbums",
+		searchPhotoURL: opts.libraryURL + "/v1/mediaItems:search",
+		mediaItemsURL:  opts.libraryURL + "/v1/mediaItems",
+		uploadsURL:     opts.libraryURL + "/v1/uploads",
+		getTokenURL:    opts.tokenURL,
+		retry:          opts.retry,
+		metrics:        opts.metrics,
+		tracer:         opts.tracer,
 	}
 }
 
@@ -64,7 +157,7 @@ func (g *googleApi) refreshAccessToken(clientID, clientSecret, refreshToken stri
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Add("cache-control", "no-cache")
-	res, err := g.client.Do(req)
+	res, err := g.do(req)
 	if err != nil {
 		return refreshResponse.AccessToken, err
 	}
@@ -92,68 +185,412 @@ func (g *googleApi) refreshAccessToken(clientID, clientSecret, refreshToken stri
 }
 
 func (g *googleApi) getAlbumList(accessToken string) ([]*GoogleAlbum, error) {
-	var (
-		googleResponse googleAlbumResponse
-		albums         []*GoogleAlbum
-	)
+	var albums []*GoogleAlbum
 
-	req, err := http.NewRequest("GET", g.getAlbumsURL, nil)
+	err := paginate(func(pageToken string) (string, error) {
+		var googleResponse googleAlbumResponse
+
+		endpoint := g.getAlbumsURL
+		if pageToken != "" {
+			query := url.Values{}
+			query.Set("pageToken", pageToken)
+			endpoint += "?" + query.Encode()
+		}
+		if err := g.callJSON("GET", endpoint, accessToken, nil, &googleResponse); err != nil {
+			return "", err
+		}
+		albums = append(albums, googleResponse.GoogleAlbums...)
+		return googleResponse.NextPageToken, nil
+	})
+	return albums, err
+}
+
+func (g *googleApi) searchPhotos(accessToken, albumID string) ([]*GooglePhoto, error) {
+	var photos []*GooglePhoto
+
+	search := searchRequest{AlbumID: albumID, PageSize: defaultLimit}
+	err := paginate(func(pageToken string) (string, error) {
+		var googleResponse googlePhotoResponse
+
+		search.PageToken = pageToken
+		if err := g.callJSON("POST", g.searchPhotoURL, accessToken, search, &googleResponse); err != nil {
+			return "", err
+		}
+		photos = append(photos, googleResponse.GooglePhotos...)
+		return googleResponse.NextPageToken, nil
+	})
 	if err != nil {
-		return albums, err
+		return photos, err
 	}
-	auth := fmt.Sprintf("Bearer %s", accessToken)
-	req.Header.Add("Authorization", auth)
-	req.Header.Add("cache-control", "no-cache")
 
-	res, err := g.client.Do(req)
+	logrus.WithFields(logrus.Fields{
+		"album": albumID,
+		"count": len(photos),
+	}).Debugln("get album photo from api")
+	return photos, nil
+}
+
+// exchangeCode exchange OAuth authorization code for the tokens.
+func (g *googleApi) exchangeCode(clientID, clientSecret, code, redirectURI string) (*Token, error) {
+	const authorizationCodeType = "authorization_code"
+	var token Token
+
+	data := url.Values{}
+	data.Set("grant_type", authorizationCodeType)
+	data.Set("code", code)
+	data.Set("client_id", clientID)
+	data.Set("client_secret", clientSecret)
+	data.Set("redirect_uri", redirectURI)
+
+	req, err := http.NewRequest("POST", g.getTokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
-		return albums, err
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("cache-control", "no-cache")
+	res, err := g.do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer func() {
 		_ = res.Body.Close()
 	}()
 
-	switch res.StatusCode {
-	case http.StatusOK:
-	case http.StatusUnauthorized:
-		return albums, unauthorizedErr
-	default:
-		return albums, errors.New(res.Status)
+	if res.StatusCode != http.StatusOK {
+		logrus.WithField("status", res.StatusCode).Errorln("bad status")
+		return nil, badStatusErr
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return albums, err
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, err
 	}
+	logrus.WithField("expires", token.ExpiresIn).Infoln("authorization code exchanged")
+	return &token, nil
+}
 
-	if err = json.Unmarshal(body, &googleResponse); err != nil {
-		return albums, err
+// searchMedia search library media items by filters page by page.
+func (g *googleApi) searchMedia(accessToken string, filter SearchFilter) ([]*GooglePhoto, error) {
+	var photos []*GooglePhoto
+
+	search := searchRequest{PageSize: defaultLimit, Filters: filter.filters()}
+	err := paginate(func(pageToken string) (string, error) {
+		var googleResponse googlePhotoResponse
+
+		search.PageToken = pageToken
+		if err := g.callJSON("POST", g.searchPhotoURL, accessToken, search, &googleResponse); err != nil {
+			return "", err
+		}
+		photos = append(photos, googleResponse.GooglePhotos...)
+		return googleResponse.NextPageToken, nil
+	})
+	if err != nil {
+		return photos, err
 	}
-	return googleResponse.GoogleAlbums, nil
+
+	logrus.WithField("count", len(photos)).Debugln("search media items from api")
+	return photos, nil
 }
 
-func (g *googleApi) searchPhotos(accessToken, albumID string) ([]*GooglePhoto, error) {
+// uploadMedia upload raw media bytes and return an upload token.
+func (g *googleApi) uploadMedia(accessToken, fileName string, content io.Reader) (string, error) {
+	req, err := http.NewRequest("POST", g.uploadsURL, content)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Add("Content-Type", "application/octet-stream")
+	req.Header.Add("X-Goog-Upload-Protocol", "raw")
+	req.Header.Add("X-Goog-Upload-File-Name", fileName)
+
+	token, err := g.call(req, accessToken)
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+// createMediaItem create media item from the upload token, album is optional.
+func (g *googleApi) createMediaItem(accessToken, albumID, uploadToken, fileName, description string) (*GooglePhoto, error) {
 	var (
-		googleResponse googlePhotoResponse
-		photos         []*GooglePhoto
+		googleResponse batchCreateResponse
+		item           newMediaItem
 	)
 
-	form := url.Values{}
-	form.Set("pageSize", strconv.Itoa(defaultLimit))
-	form.Set("albumId", albumID)
-	req, err := http.NewRequest("POST", g.searchPhotoURL, strings.NewReader(form.Encode()))
+	item.Description = description
+	item.SimpleMediaItem.FileName = fileName
+	item.SimpleMediaItem.UploadToken = uploadToken
+	payload := batchCreateRequest{AlbumID: albumID, NewMediaItems: []newMediaItem{item}}
+	if err := g.callJSON("POST", g.mediaItemsURL+":batchCreate", accessToken, payload, &googleResponse); err != nil {
+		return nil, err
+	}
+	if len(googleResponse.NewMediaItemResults) == 0 {
+		return nil, badStatusErr
+	}
+
+	result := googleResponse.NewMediaItemResults[0]
+	if result.Status.Code != 0 || result.MediaItem == nil {
+		return nil, errors.New(result.Status.Message)
+	}
+	return result.MediaItem, nil
+}
+
+// listMediaItems fetch all media items of the library page by page.
+func (g *googleApi) listMediaItems(accessToken string) ([]*GooglePhoto, error) {
+	var photos []*GooglePhoto
+
+	err := paginate(func(pageToken string) (string, error) {
+		var googleResponse googlePhotoResponse
+
+		query := url.Values{}
+		query.Set("pageSize", strconv.Itoa(defaultLimit))
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		if err := g.callJSON("GET", g.mediaItemsURL+"?"+query.Encode(), accessToken, nil, &googleResponse); err != nil {
+			return "", err
+		}
+		photos = append(photos, googleResponse.GooglePhotos...)
+		return googleResponse.NextPageToken, nil
+	})
 	if err != nil {
 		return photos, err
 	}
 
+	logrus.WithField("count", len(photos)).Debugln("get library media items from api")
+	return photos, nil
+}
+
+// download open media content by url starting from the offset, caller must close returned body.
+// Returned flag reports whether the server honored the Range request.
+func (g *googleApi) download(url string, offset int64) (io.ReadCloser, bool, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	res, err := g.send(g.downloadClient(), req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, false, nil
+	case http.StatusPartialContent:
+		return res.Body, true, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		_ = res.Body.Close()
+		return nil, false, rangeErr
+	default:
+		_ = res.Body.Close()
+		logrus.WithField("status", res.StatusCode).Errorln("bad status")
+		return nil, false, badStatusErr
+	}
+}
+
+// downloadClient return the client reading media content.
+func (g *googleApi) downloadClient() *http.Client {
+	if g.downloader == nil {
+		return g.client
+	}
+	return g.downloader
+}
+
+// downloadTransport make the transport of media downloads, the http timeout bounds
+// waiting for response headers only, so large originals and videos are not cut off.
+// A custom transport is used as is.
+func downloadTransport(opts *options) http.RoundTripper {
+	if opts.transport != nil {
+		return opts.transport
+	}
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		ResponseHeaderTimeout: opts.httpTimeout,
+	}
+}
+
+// getMediaItem fetch a single media item, the item base url is always fresh.
+func (g *googleApi) getMediaItem(accessToken, mediaID string) (*GooglePhoto, error) {
+	var photo GooglePhoto
+
+	if err := g.callJSON("GET", g.mediaItemsURL+"/"+url.PathEscape(mediaID), accessToken, nil, &photo); err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+// listSharedAlbums fetch all albums shared with the user page by page.
+func (g *googleApi) listSharedAlbums(accessToken string) ([]*GoogleAlbum, error) {
+	var albums []*GoogleAlbum
+
+	err := paginate(func(pageToken string) (string, error) {
+		var googleResponse sharedAlbumResponse
+
+		query := url.Values{}
+		query.Set("pageSize", strconv.Itoa(defaultAlbumLimit))
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		if err := g.callJSON("GET", g.sharedAlbumURL+"?"+query.Encode(), accessToken, nil, &googleResponse); err != nil {
+			return "", err
+		}
+		albums = append(albums, googleResponse.SharedAlbums...)
+		return googleResponse.NextPageToken, nil
+	})
+	if err != nil {
+		return albums, err
+	}
+
+	logrus.WithField("count", len(albums)).Debugln("get shared albums from api")
+	return albums, nil
+}
+
+// joinSharedAlbum join shared album by share token.
+func (g *googleApi) joinSharedAlbum(accessToken, shareToken string) (*GoogleAlbum, error) {
+	var googleResponse struct {
+		Album *GoogleAlbum `json:"album"`
+	}
+
+	payload := map[string]string{"shareToken": shareToken}
+	if err := g.callJSON("POST", g.sharedAlbumURL+":join", accessToken, payload, &googleResponse); err != nil {
+		return nil, err
+	}
+	if googleResponse.Album == nil {
+		return nil, badStatusErr
+	}
+	return googleResponse.Album, nil
+}
+
+// leaveSharedAlbum leave previously joined shared album.
+func (g *googleApi) leaveSharedAlbum(accessToken, shareToken string) error {
+	payload := map[string]string{"shareToken": shareToken}
+	return g.callJSON("POST", g.sharedAlbumURL+":leave", accessToken, payload, nil)
+}
+
+// shareAlbum mark album as shared and accessible to other users.
+func (g *googleApi) shareAlbum(accessToken, albumID string, options SharedAlbumOptions) (*ShareInfo, error) {
+	var googleResponse struct {
+		ShareInfo *ShareInfo `json:"shareInfo"`
+	}
+
+	payload := map[string]SharedAlbumOptions{"sharedAlbumOptions": options}
+	if err := g.callJSON("POST", g.albumURL(albumID)+":share", accessToken, payload, &googleResponse); err != nil {
+		return nil, err
+	}
+	if googleResponse.ShareInfo == nil {
+		return nil, badStatusErr
+	}
+	return googleResponse.ShareInfo, nil
+}
+
+// unshareAlbum mark album as private, non-owners lose access to it.
+func (g *googleApi) unshareAlbum(accessToken, albumID string) error {
+	return g.callJSON("POST", g.albumURL(albumID)+":unshare", accessToken, struct{}{}, nil)
+}
+
+// addEnrichment add enrichment item to the album at the position and return its ID.
+func (g *googleApi) addEnrichment(accessToken, albumID string, enrichment NewEnrichmentItem, position AlbumPosition) (string, error) {
+	var googleResponse struct {
+		EnrichmentItem struct {
+			ID string `json:"id"`
+		} `json:"enrichmentItem"`
+	}
+
+	payload := addEnrichmentRequest{NewEnrichmentItem: enrichment, AlbumPosition: position}
+	if err := g.callJSON("POST", g.albumURL(albumID)+":addEnrichment", accessToken, payload, &googleResponse); err != nil {
+		return "", err
+	}
+	if googleResponse.EnrichmentItem.ID == "" {
+		return "", badStatusErr
+	}
+	return googleResponse.EnrichmentItem.ID, nil
+}
+
+// patchMediaItem update description of the media item.
+func (g *googleApi) patchMediaItem(accessToken, mediaID, description string) (*GooglePhoto, error) {
+	var photo GooglePhoto
+
+	query := url.Values{}
+	query.Set("updateMask", "description")
+	endpoint := g.mediaItemsURL + "/" + url.PathEscape(mediaID) + "?" + query.Encode()
+	payload := map[string]string{"description": description}
+	if err := g.callJSON("PATCH", endpoint, accessToken, payload, &photo); err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+// patchAlbum update title of the album.
+func (g *googleApi) patchAlbum(accessToken, albumID, title string) (*GoogleAlbum, error) {
+	var album GoogleAlbum
+
+	query := url.Values{}
+	query.Set("updateMask", "title")
+	endpoint := g.albumURL(albumID) + "?" + query.Encode()
+	payload := map[string]string{"title": title}
+	if err := g.callJSON("PATCH", endpoint, accessToken, payload, &album); err != nil {
+		return nil, err
+	}
+	return &album, nil
+}
+
+// albumURL build url of the album resource.
+func (g *googleApi) albumURL(albumID string) string {
+	return g.getAlbumsURL + "/" + url.PathEscape(albumID)
+}
+
+// callJSON send authorized request with JSON payload and decode JSON response into result,
+// both payload and result are optional.
+func (g *googleApi) callJSON(method, url, accessToken string, payload, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	data, err := g.call(req, accessToken)
+	if err != nil || result == nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+// call send authorized request and return the body of the successful response.
+// 401 is reported as unauthorizedErr, 404 as notFoundErr, other failures by the status.
+func (g *googleApi) call(req *http.Request, accessToken string) ([]byte, error) {
 	auth := fmt.Sprintf("Bearer %s", accessToken)
 	req.Header.Add("Authorization", auth)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Add("cache-control", "no-cache")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := g.do(req)
 	if err != nil {
-		return photos, err
+		return nil, err
 	}
 	defer func() {
 		_ = res.Body.Close()
@@ -162,25 +599,26 @@ func (g *googleApi) searchPhotos(accessToken, albumID string) ([]*GooglePhoto, e
 	switch res.StatusCode {
 	case http.StatusOK:
 	case http.StatusUnauthorized:
-		return photos, unauthorizedErr
+		return nil, unauthorizedErr
+	case http.StatusNotFound:
+		return nil, notFoundErr
 	default:
-		return photos, errors.New(res.Status)
-	}
-
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return photos, err
+		return nil, errors.New(res.Status)
 	}
+	return ioutil.ReadAll(res.Body)
+}
 
-	if err := json.Unmarshal(body, &googleResponse); err != nil {
-		return photos, err
+// paginate call fetch with the token of every next page, starting with an empty one,
+// until fetch return no next page token.
+func paginate(fetch func(pageToken string) (nextPageToken string, err error)) error {
+	var pageToken string
+	for {
+		next, err := fetch(pageToken)
+		if err != nil || next == "" {
+			return err
+		}
+		pageToken = next
 	}
-
-	logrus.WithFields(logrus.Fields{
-		"album": albumID,
-		"count": len(googleResponse.GooglePhotos),
-	}).Debugln("get album photo from api")
-	return googleResponse.GooglePhotos, err
 }
 
 // urlIsValid check the link to the photo still expired.
@@ -189,7 +627,7 @@ func (g *googleApi) urlIsValid(url string) bool {
 	if err != nil {
 		return false
 	}
-	res, err := g.client.Do(req)
+	res, err := g.do(req)
 	if err != nil {
 		return false
 	}
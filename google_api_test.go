@@ -2,9 +2,11 @@ package gphoto
 
 import (
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,10 +20,19 @@ func TestNewGoogleApi(t *testing.T) {
 			Timeout: time.Second * 10,
 		},
 		getAlbumsURL:   "https://photoslibrary.googleapis.com/v1/albums",
+		sharedAlbumURL: "https://photoslibrary.googleapis.com/v1/sharedAlbums",
 		searchPhotoURL: "https://photoslibrary.googleapis.com/v1/mediaItems:search",
+		mediaItemsURL:  "https://photoslibrary.googleapis.com/v1/mediaItems",
+		uploadsURL:     "https://photoslibrary.googleapis.com/v1/uploads",
 		getTokenURL:    "https://accounts.google.com/o/oauth2/token",
 	}
-	if got := NewGoogleApi(); !reflect.DeepEqual(got, want) {
+	got := NewGoogleApi()
+	if assert.NotNil(t, got.downloader) {
+		assert.Zero(t, got.downloader.Timeout, "downloads are not bounded by the http timeout")
+		assert.Equal(t, time.Second*10, got.downloader.Transport.(*http.Transport).ResponseHeaderTimeout)
+	}
+	got.downloader = nil
+	if !reflect.DeepEqual(got, want) {
 		t.Errorf("NewGoogleApi() = %v, want %v", got, want)
 	}
 }
@@ -113,7 +124,7 @@ func Test_googleApi_searchPhotos(t *testing.T) {
 				accessToken: "accesstoken",
 				path:        "/get-album-list",
 			},
-			wantErr: errors.New("404 Not Found"),
+			wantErr: notFoundErr,
 		},
 	}
 	for _, tt := range tests {
@@ -188,7 +199,7 @@ func Test_googleApi_getAlbumList(t *testing.T) {
 				accessToken: "accesstoken",
 				path:        "/get-album-list",
 			},
-			wantErr: errors.New("404 Not Found"),
+			wantErr: notFoundErr,
 		},
 	}
 	for _, tt := range tests {
@@ -285,3 +296,270 @@ func Test_googleApi_refreshAccessToken(t *testing.T) {
 		})
 	}
 }
+
+func Test_googleApi_listMediaItems(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		pages      map[string]string
+		want       []string
+		wantErr    error
+	}{
+		{
+			name:       "StatusOK",
+			statusCode: http.StatusOK,
+			pages: map[string]string{
+				"":     `{"mediaItems":[{"id":"first"}],"nextPageToken":"next"}`,
+				"next": `{"mediaItems":[{"id":"second"}]}`,
+			},
+			want: []string{"first", "second"},
+		},
+		{
+			name:       "StatusUnauthorized",
+			statusCode: http.StatusUnauthorized,
+			wantErr:    unauthorizedErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(tt.statusCode)
+				assert.Equal(t, "Bearer accesstoken", req.Header.Get("Authorization"))
+				_, _ = rw.Write([]byte(tt.pages[req.URL.Query().Get("pageToken")]))
+			}))
+			defer server.Close()
+
+			api := googleApi{client: server.Client(), mediaItemsURL: server.URL + "/media-items"}
+			photos, err := api.listMediaItems("accesstoken")
+			if err == nil {
+				var ids []string
+				for _, photo := range photos {
+					ids = append(ids, photo.ID)
+				}
+				assert.Equal(t, tt.want, ids)
+			} else {
+				assert.Equal(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_googleApi_download(t *testing.T) {
+	tests := []struct {
+		name        string
+		offset      int64
+		want        string
+		wantResumed bool
+		wantErr     error
+	}{
+		{
+			name: "full content",
+			want: "content",
+		},
+		{
+			name:        "resume from offset",
+			offset:      3,
+			want:        "tent",
+			wantResumed: true,
+		},
+		{
+			name:    "offset out of range",
+			offset:  100,
+			wantErr: rangeErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				http.ServeContent(rw, req, "photo.jpg", time.Time{}, strings.NewReader("content"))
+			}))
+			defer server.Close()
+
+			api := googleApi{client: server.Client()}
+			body, resumed, err := api.download(server.URL+"/photo", tt.offset)
+			if err == nil {
+				defer body.Close()
+				data, err := ioutil.ReadAll(body)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, string(data))
+				assert.Equal(t, tt.wantResumed, resumed)
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_googleApi_downloadTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte("first "))
+		rw.(http.Flusher).Flush()
+		time.Sleep(150 * time.Millisecond)
+		_, _ = rw.Write([]byte("second"))
+	}))
+	defer server.Close()
+
+	opts := defaultOptions()
+	opts.httpTimeout = 50 * time.Millisecond
+	api := newGoogleApi(opts)
+
+	body, _, err := api.download(server.URL+"/content", 0)
+	if assert.NoError(t, err) {
+		defer body.Close()
+		content, err := ioutil.ReadAll(body)
+		assert.NoError(t, err, "reading content is not bounded by the http timeout")
+		assert.Equal(t, "first second", string(content))
+	}
+
+	res, err := api.client.Get(server.URL + "/content")
+	if err == nil {
+		_, err = ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+	}
+	assert.Error(t, err, "API requests keep the http timeout")
+}
+
+func Test_googleApi_createMediaItem(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		payload    string
+		want       string
+		wantErr    error
+	}{
+		{
+			name:       "StatusOK",
+			statusCode: http.StatusOK,
+			payload:    `{"newMediaItemResults":[{"uploadToken":"token","status":{"message":"Success"},"mediaItem":{"id":"created"}}]}`,
+			want:       "created",
+		},
+		{
+			name:       "item status error",
+			statusCode: http.StatusOK,
+			payload:    `{"newMediaItemResults":[{"uploadToken":"token","status":{"code":3,"message":"invalid token"}}]}`,
+			wantErr:    errors.New("invalid token"),
+		},
+		{
+			name:       "StatusUnauthorized",
+			statusCode: http.StatusUnauthorized,
+			wantErr:    unauthorizedErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "/media-items:batchCreate", req.URL.String())
+				body, _ := ioutil.ReadAll(req.Body)
+				assert.JSONEq(t, `{"albumId":"album","newMediaItems":[{"simpleMediaItem":{"fileName":"photo.jpg","uploadToken":"token"}}]}`, string(body))
+				rw.WriteHeader(tt.statusCode)
+				_, _ = rw.Write([]byte(tt.payload))
+			}))
+			defer server.Close()
+
+			api := googleApi{client: server.Client(), mediaItemsURL: server.URL + "/media-items"}
+			photo, err := api.createMediaItem("accesstoken", "album", "token", "photo.jpg", "")
+			if err == nil {
+				assert.Equal(t, tt.want, photo.ID)
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_googleApi_listSharedAlbums(t *testing.T) {
+	pages := map[string]string{
+		"":     `{"sharedAlbums":[{"id":"first","shareInfo":{"shareToken":"token","isJoined":true}}],"nextPageToken":"next"}`,
+		"next": `{"sharedAlbums":[{"id":"second"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/shared-albums", req.URL.Path)
+		assert.Equal(t, "Bearer accesstoken", req.Header.Get("Authorization"))
+		_, _ = rw.Write([]byte(pages[req.URL.Query().Get("pageToken")]))
+	}))
+	defer server.Close()
+
+	api := googleApi{client: server.Client(), sharedAlbumURL: server.URL + "/shared-albums"}
+	albums, err := api.listSharedAlbums("accesstoken")
+	assert.NoError(t, err)
+	if assert.Len(t, albums, 2) {
+		assert.Equal(t, &ShareInfo{ShareToken: "token", IsJoined: true}, albums[0].ShareInfo)
+		assert.Nil(t, albums[1].ShareInfo)
+	}
+}
+
+func Test_googleApi_shareAlbum(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		payload    string
+		want       *ShareInfo
+		wantErr    error
+	}{
+		{
+			name:       "StatusOK",
+			statusCode: http.StatusOK,
+			payload:    `{"shareInfo":{"shareableUrl":"http://share","shareToken":"token","isOwned":true,"sharedAlbumOptions":{"isCollaborative":true}}}`,
+			want: &ShareInfo{
+				SharedAlbumOptions: SharedAlbumOptions{IsCollaborative: true},
+				ShareableURL:       "http://share",
+				ShareToken:         "token",
+				IsOwned:            true,
+			},
+		},
+		{
+			name:       "StatusUnauthorized",
+			statusCode: http.StatusUnauthorized,
+			wantErr:    unauthorizedErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "/albums/album:share", req.URL.Path)
+				body, _ := ioutil.ReadAll(req.Body)
+				assert.JSONEq(t, `{"sharedAlbumOptions":{"isCollaborative":true,"isCommentable":false}}`, string(body))
+				rw.WriteHeader(tt.statusCode)
+				_, _ = rw.Write([]byte(tt.payload))
+			}))
+			defer server.Close()
+
+			api := googleApi{client: server.Client(), getAlbumsURL: server.URL + "/albums"}
+			shareInfo, err := api.shareAlbum("accesstoken", "album", SharedAlbumOptions{IsCollaborative: true})
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, shareInfo)
+		})
+	}
+}
+
+func Test_googleApi_addEnrichment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/albums/album:addEnrichment", req.URL.Path)
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.JSONEq(t, `{"newEnrichmentItem":{"textEnrichment":{"text":"caption"}},`+
+			`"albumPosition":{"position":"AFTER_MEDIA_ITEM","relativeMediaItemId":"photo"}}`, string(body))
+		_, _ = rw.Write([]byte(`{"enrichmentItem":{"id":"enrichment"}}`))
+	}))
+	defer server.Close()
+
+	api := googleApi{client: server.Client(), getAlbumsURL: server.URL + "/albums"}
+	id, err := api.addEnrichment("accesstoken", "album", NewTextEnrichment("caption"), AfterMediaItem("photo"))
+	assert.NoError(t, err)
+	assert.Equal(t, "enrichment", id)
+}
+
+func Test_googleApi_patchMediaItem(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "PATCH", req.Method)
+		assert.Equal(t, "/media-items/photo?updateMask=description", req.URL.String())
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.JSONEq(t, `{"description":"new"}`, string(body))
+		_, _ = rw.Write([]byte(`{"id":"photo","description":"new"}`))
+	}))
+	defer server.Close()
+
+	api := googleApi{client: server.Client(), mediaItemsURL: server.URL + "/media-items"}
+	photo, err := api.patchMediaItem("accesstoken", "photo", "new")
+	assert.NoError(t, err)
+	assert.Equal(t, &GooglePhoto{ID: "photo", Description: "new"}, photo)
+}
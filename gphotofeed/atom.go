@@ -0,0 +1,106 @@
+package gphotofeed
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"time"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Links     []atomLink  `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteAtom write the feed as Atom, photos are linked as enclosures.
+// Without WithAuthor the feed title is the feed author unless every entry has one.
+func (f *Feed) WriteAtom(w io.Writer) error {
+	doc := atomFeed{
+		XMLNS:   atomNamespace,
+		ID:      "urn:gphoto:album:" + url.PathEscape(f.album.ID),
+		Title:   f.title(),
+		Updated: atomDate(f.updated()),
+	}
+	if f.author != "" {
+		doc.Author = &atomPerson{Name: f.author}
+	} else if !f.entryAuthors() {
+		doc.Author = &atomPerson{Name: doc.Title}
+	}
+	if f.link != "" {
+		doc.Links = append(doc.Links, atomLink{Href: f.link, Rel: "alternate"})
+	}
+	if f.feedURL != "" {
+		doc.Links = append(doc.Links, atomLink{Href: f.feedURL, Rel: "self", Type: "application/atom+xml"})
+	}
+
+	for _, e := range f.entries {
+		entry := atomEntry{
+			ID:        "urn:gphoto:media:" + url.PathEscape(e.id),
+			Title:     e.title,
+			Updated:   atomDate(e.created),
+			Published: atomDate(e.created),
+			Links:     []atomLink{{Href: e.imageURL, Rel: "enclosure", Type: e.photo.MimeType}},
+			Content:   atomContent{Type: "html", Value: e.contentHTML()},
+		}
+		if e.link != "" {
+			entry.Links = append([]atomLink{{Href: e.link, Rel: "alternate"}}, entry.Links...)
+		}
+		if e.author != "" {
+			entry.Author = &atomPerson{Name: e.author}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return writeXML(w, doc)
+}
+
+// entryAuthors report whether every entry has an author, Atom requires
+// the feed author otherwise (RFC 4287, section 4.1.1).
+func (f *Feed) entryAuthors() bool {
+	for _, e := range f.entries {
+		if e.author == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// atomDate format t as RFC 3339 date, Atom requires a date so zero time is the Unix epoch.
+func atomDate(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
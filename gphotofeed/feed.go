@@ -0,0 +1,132 @@
+// Package gphotofeed publish an album and its photos as RSS 2.0, Atom and JSON Feed.
+package gphotofeed
+
+import (
+	"html"
+	"sort"
+	"time"
+
+	"github.com/ihippik/gphoto"
+)
+
+// Feed is an album with its photos, the newest photo first.
+type Feed struct {
+	album    *gphoto.GoogleAlbum
+	entries  []*entry
+	link     string
+	feedURL  string
+	author   string
+	imageURL func(photo *gphoto.GooglePhoto) string
+}
+
+// Option configure Feed created by New.
+type Option func(*Feed)
+
+// WithLink set the page of the album, ProductURL of the album is used by default.
+func WithLink(link string) Option {
+	return func(f *Feed) {
+		f.link = link
+	}
+}
+
+// WithFeedURL set the url the feed is published at.
+func WithFeedURL(feedURL string) Option {
+	return func(f *Feed) {
+		f.feedURL = feedURL
+	}
+}
+
+// WithAuthor set the feed author, entries of shared albums keep their contributors.
+func WithAuthor(name string) Option {
+	return func(f *Feed) {
+		f.author = name
+	}
+}
+
+// WithImageURL set the url of the photo content in entries, e.g. of gphotohttp.ImageProxy.
+// Base urls of photos are used by default, they expire after about an hour.
+func WithImageURL(imageURL func(photo *gphoto.GooglePhoto) string) Option {
+	return func(f *Feed) {
+		f.imageURL = imageURL
+	}
+}
+
+// entry is a photo as written to feeds.
+type entry struct {
+	photo    *gphoto.GooglePhoto
+	id       string
+	title    string
+	link     string
+	imageURL string
+	author   string
+	created  time.Time
+}
+
+// New make Feed of the album photos.
+func New(album *gphoto.GoogleAlbum, photos []*gphoto.GooglePhoto, opts ...Option) *Feed {
+	f := &Feed{
+		album: album,
+		link:  album.ProductURL,
+		imageURL: func(photo *gphoto.GooglePhoto) string {
+			return photo.BaseURL
+		},
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	for _, photo := range photos {
+		e := &entry{
+			photo:    photo,
+			id:       photo.ID,
+			title:    photo.Filename,
+			link:     photo.ProductURL,
+			imageURL: f.imageURL(photo),
+			created:  photo.MediaMetadata.CreationTime,
+		}
+		if e.title == "" {
+			e.title = photo.ID
+		}
+		if photo.ContributorInfo != nil {
+			e.author = photo.ContributorInfo.DisplayName
+		}
+		f.entries = append(f.entries, e)
+	}
+	sort.SliceStable(f.entries, func(i, j int) bool {
+		return f.entries[i].created.After(f.entries[j].created)
+	})
+	return f
+}
+
+// title return the album title, untitled albums are named by their id.
+func (f *Feed) title() string {
+	if f.album.Title != "" {
+		return f.album.Title
+	}
+	return f.album.ID
+}
+
+// updated return creation time of the newest photo.
+func (f *Feed) updated() time.Time {
+	if len(f.entries) == 0 {
+		return time.Time{}
+	}
+	return f.entries[0].created
+}
+
+// medium return the kind of the photo for media:content.
+func (e *entry) medium() string {
+	if e.photo.IsVideo() {
+		return "video"
+	}
+	return "image"
+}
+
+// contentHTML render the photo with its description.
+func (e *entry) contentHTML() string {
+	var content string
+	if e.photo.Description != "" {
+		content = "<p>" + html.EscapeString(e.photo.Description) + "</p>"
+	}
+	return content + `<img src="` + html.EscapeString(e.imageURL) + `" alt="` + html.EscapeString(e.title) + `">`
+}
@@ -0,0 +1,160 @@
+package gphotofeed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/ihippik/gphoto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFeed() *Feed {
+	album := &gphoto.GoogleAlbum{ID: "album", Title: "Holidays", ProductURL: "https://photos.google.com/album"}
+	older := &gphoto.GooglePhoto{
+		ID:              "older",
+		Filename:        "beach.jpg",
+		Description:     "Sea & sand",
+		MimeType:        "image/jpeg",
+		ProductURL:      "https://photos.google.com/older",
+		ContributorInfo: &gphoto.ContributorInfo{DisplayName: "Ann"},
+	}
+	older.MediaMetadata.CreationTime = time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	older.MediaMetadata.Width, older.MediaMetadata.Height = 800, 600
+	newer := &gphoto.GooglePhoto{ID: "newer", Filename: "clip.mp4", MimeType: "video/mp4"}
+	newer.MediaMetadata.CreationTime = time.Date(2020, 5, 2, 12, 0, 0, 0, time.UTC)
+
+	return New(album, []*gphoto.GooglePhoto{older, newer},
+		WithFeedURL("https://example.com/feed"),
+		WithAuthor("Bob"),
+		WithImageURL(func(photo *gphoto.GooglePhoto) string {
+			return "https://example.com/img/" + photo.ID
+		}),
+	)
+}
+
+func TestFeed_WriteRSS(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testFeed().WriteRSS(&buf))
+
+	var doc struct {
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title   string `xml:"title"`
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+				Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Content struct {
+					URL    string `xml:"url,attr"`
+					Medium string `xml:"medium,attr"`
+					Width  int    `xml:"width,attr"`
+				} `xml:"http://search.yahoo.com/mrss/ content"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "Holidays", doc.Channel.Title)
+	assert.Contains(t, buf.String(), "<link>https://photos.google.com/album</link>")
+	assert.Equal(t, "Sat, 02 May 2020 12:00:00 +0000", doc.Channel.LastBuildDate)
+	require.Len(t, doc.Channel.Items, 2)
+
+	newer, older := doc.Channel.Items[0], doc.Channel.Items[1]
+	assert.Equal(t, "newer", newer.GUID, "the newest photo first")
+	assert.Equal(t, "video", newer.Content.Medium)
+	assert.Equal(t, "beach.jpg", older.Title)
+	assert.Equal(t, "older", older.GUID)
+	assert.Equal(t, "Fri, 01 May 2020 12:00:00 +0000", older.PubDate)
+	assert.Equal(t, "Ann", older.Creator)
+	assert.Equal(t, "https://example.com/img/older", older.Content.URL)
+	assert.Equal(t, "image", older.Content.Medium)
+	assert.Equal(t, 800, older.Content.Width)
+	assert.Contains(t, buf.String(), `<atom:link href="https://example.com/feed" rel="self" type="application/rss+xml"></atom:link>`)
+}
+
+func TestFeed_WriteAtom(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testFeed().WriteAtom(&buf))
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Author  string   `xml:"author>name"`
+		Entries []struct {
+			ID        string `xml:"id"`
+			Title     string `xml:"title"`
+			Published string `xml:"published"`
+			Links     []struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+			} `xml:"link"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "urn:gphoto:album:album", doc.ID)
+	assert.Equal(t, "2020-05-02T12:00:00Z", doc.Updated)
+	assert.Equal(t, "Bob", doc.Author)
+	require.Len(t, doc.Entries, 2)
+
+	older := doc.Entries[1]
+	assert.Equal(t, "urn:gphoto:media:older", older.ID)
+	assert.Equal(t, "2020-05-01T12:00:00Z", older.Published)
+	require.Len(t, older.Links, 2)
+	assert.Equal(t, "alternate", older.Links[0].Rel)
+	assert.Equal(t, "https://example.com/img/older", older.Links[1].Href)
+	assert.Equal(t, `<p>Sea &amp; sand</p><img src="https://example.com/img/older" alt="beach.jpg">`, older.Content)
+}
+
+func TestFeed_WriteAtomAuthor(t *testing.T) {
+	album := &gphoto.GoogleAlbum{ID: "album", Title: "Holidays"}
+	type atomDoc struct {
+		Author  *string `xml:"author>name"`
+		Entries []struct {
+			Author string `xml:"author>name"`
+		} `xml:"entry"`
+	}
+	var doc atomDoc
+
+	var buf bytes.Buffer
+	require.NoError(t, New(album, []*gphoto.GooglePhoto{{ID: "photo"}}).WriteAtom(&buf))
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	require.NotNil(t, doc.Author)
+	assert.Equal(t, "Holidays", *doc.Author, "the feed title is the fallback author")
+
+	shared := &gphoto.GooglePhoto{ID: "shared", ContributorInfo: &gphoto.ContributorInfo{DisplayName: "Ann"}}
+	buf.Reset()
+	doc = atomDoc{}
+	require.NoError(t, New(album, []*gphoto.GooglePhoto{shared}).WriteAtom(&buf))
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Nil(t, doc.Author, "every entry has an author")
+	assert.Equal(t, "Ann", doc.Entries[0].Author)
+}
+
+func TestFeed_WriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testFeed().WriteJSON(&buf))
+
+	var doc jsonFeed
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, jsonFeedVersion, doc.Version)
+	assert.Equal(t, "https://example.com/feed", doc.FeedURL)
+	require.Len(t, doc.Items, 2)
+
+	older := doc.Items[1]
+	assert.Equal(t, "older", older.ID)
+	assert.Equal(t, "2020-05-01T12:00:00Z", older.DatePublished)
+	assert.Equal(t, "Sea & sand", older.Summary)
+	assert.Equal(t, "https://example.com/img/older", older.Image)
+	assert.Equal(t, []jsonFeedAttachment{{URL: "https://example.com/img/older", MimeType: "image/jpeg"}}, older.Attachments)
+	assert.Empty(t, doc.Items[0].Image, "videos have no image")
+
+	buf.Reset()
+	require.NoError(t, New(&gphoto.GoogleAlbum{ID: "empty"}, nil).WriteJSON(&buf))
+	assert.Contains(t, buf.String(), `"items": []`)
+}
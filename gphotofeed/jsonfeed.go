@@ -0,0 +1,78 @@
+package gphotofeed
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url,omitempty"`
+	FeedURL     string           `json:"feed_url,omitempty"`
+	Authors     []jsonFeedAuthor `json:"authors,omitempty"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url,omitempty"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	Summary       string               `json:"summary,omitempty"`
+	Image         string               `json:"image,omitempty"`
+	DatePublished string               `json:"date_published,omitempty"`
+	Authors       []jsonFeedAuthor     `json:"authors,omitempty"`
+	Attachments   []jsonFeedAttachment `json:"attachments"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedAttachment struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+}
+
+// WriteJSON write the feed as JSON Feed 1.1, photos are attachments of items.
+func (f *Feed) WriteJSON(w io.Writer) error {
+	doc := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       f.title(),
+		HomePageURL: f.link,
+		FeedURL:     f.feedURL,
+		Items:       []jsonFeedItem{},
+	}
+	if f.author != "" {
+		doc.Authors = []jsonFeedAuthor{{Name: f.author}}
+	}
+
+	for _, e := range f.entries {
+		item := jsonFeedItem{
+			ID:          e.id,
+			URL:         e.link,
+			Title:       e.title,
+			ContentHTML: e.contentHTML(),
+			Summary:     e.photo.Description,
+			Attachments: []jsonFeedAttachment{{URL: e.imageURL, MimeType: e.photo.MimeType}},
+		}
+		if !e.photo.IsVideo() {
+			item.Image = e.imageURL
+		}
+		if !e.created.IsZero() {
+			item.DatePublished = e.created.UTC().Format(time.RFC3339)
+		}
+		if e.author != "" {
+			item.Authors = []jsonFeedAuthor{{Name: e.author}}
+		}
+		doc.Items = append(doc.Items, item)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,115 @@
+package gphotofeed
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const (
+	mediaNamespace = "http://search.yahoo.com/mrss/"
+	dcNamespace    = "http://purl.org/dc/elements/1.1/"
+)
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Media   string     `xml:"xmlns:media,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Atom    string     `xml:"xmlns:atom,attr,omitempty"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          *atomLink `xml:"atom:link,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link,omitempty"`
+	Description string       `xml:"description"`
+	Author      string       `xml:"dc:creator,omitempty"`
+	GUID        rssGUID      `xml:"guid"`
+	PubDate     string       `xml:"pubDate,omitempty"`
+	Content     mediaContent `xml:"media:content"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type mediaContent struct {
+	URL         string `xml:"url,attr"`
+	Type        string `xml:"type,attr,omitempty"`
+	Medium      string `xml:"medium,attr"`
+	Width       int64  `xml:"width,attr,omitempty"`
+	Height      int64  `xml:"height,attr,omitempty"`
+	Description string `xml:"media:description,omitempty"`
+}
+
+// WriteRSS write the feed as RSS 2.0, photos are attached as media:content.
+func (f *Feed) WriteRSS(w io.Writer) error {
+	doc := rss{
+		Version: "2.0",
+		Media:   mediaNamespace,
+		DC:      dcNamespace,
+		Channel: rssChannel{
+			Title:         f.title(),
+			Link:          f.link,
+			Description:   f.title(),
+			LastBuildDate: rssDate(f.updated()),
+		},
+	}
+	if f.feedURL != "" {
+		doc.Atom = atomNamespace
+		doc.Channel.Self = &atomLink{Href: f.feedURL, Rel: "self", Type: "application/rss+xml"}
+	}
+
+	for _, e := range f.entries {
+		item := rssItem{
+			Title:       e.title,
+			Link:        e.link,
+			Description: e.contentHTML(),
+			Author:      e.author,
+			GUID:        rssGUID{Value: e.id},
+			PubDate:     rssDate(e.created),
+			Content: mediaContent{
+				URL:         e.imageURL,
+				Type:        e.photo.MimeType,
+				Medium:      e.medium(),
+				Width:       e.photo.MediaMetadata.Width,
+				Height:      e.photo.MediaMetadata.Height,
+				Description: e.photo.Description,
+			},
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return writeXML(w, doc)
+}
+
+// rssDate format t as RFC 822 date, zero time is omitted.
+func rssDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC1123Z)
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,452 @@
+// Package gphotogallery write albums of a gphoto.Client as a self-contained static HTML gallery.
+package gphotogallery
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ihippik/gphoto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	manifestFile = ".gallery.json"
+	partSuffix   = ".part"
+	mediaDir     = "media"
+	albumsDir    = "albums"
+)
+
+// Default sizes of downloaded variants.
+var (
+	DefaultThumbSize = gphoto.ImageSize{Width: 400, Height: 400, Crop: true}
+	DefaultImageSize = gphoto.ImageSize{Width: 1600, Height: 1600}
+)
+
+var (
+	galleryErr   = errors.New("generate gallery error")
+	templatesErr = errors.New("parse gallery templates error")
+)
+
+// Options describe what to publish and where.
+type Options struct {
+	// Dir is the root directory of the site.
+	Dir string
+	// Title of the index page, "Albums" by default.
+	Title string
+	// Albums limits the gallery to the given album IDs, every album when empty.
+	Albums []string
+	// ThumbSize and ImageSize are variants shown in album grids and on photo pages,
+	// DefaultThumbSize and DefaultImageSize when zero.
+	ThumbSize gphoto.ImageSize
+	ImageSize gphoto.ImageSize
+	// Templates is a directory of *.html files overriding the default templates
+	// "head", "index.html", "album.html" and "photo.html" by file or define name.
+	Templates string
+}
+
+// Report summarize a generation run.
+type Report struct {
+	Albums     int
+	Photos     int
+	Downloaded int
+	Written    int
+	Unchanged  int
+	Removed    int
+}
+
+// Site is the data of the index page.
+type Site struct {
+	Title  string
+	Albums []*Album
+}
+
+// Album is the data of the album page.
+type Album struct {
+	*gphoto.GoogleAlbum
+	// Page is the path of the album page relative to the site root.
+	Page string
+	// Cover is the thumbnail of the album cover relative to the site root.
+	Cover  string
+	Photos []*Photo
+	Site   *Site
+}
+
+// Photo is the data of the photo page.
+type Photo struct {
+	*gphoto.GooglePhoto
+	Album *Album
+	// Page, Thumb and Image are paths relative to the site root.
+	Page  string
+	Thumb string
+	Image string
+	Prev  *Photo
+	Next  *Photo
+}
+
+// Field is a labeled value shown on the photo page.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Root return the relative path from the page of the album to the site root.
+func (a *Album) Root() string {
+	return relativeRoot(a.Page)
+}
+
+// Root return the relative path from the page of the photo to the site root.
+func (p *Photo) Root() string {
+	return relativeRoot(p.Page)
+}
+
+// EXIF return camera fields of the photo, missing ones are skipped.
+func (p *Photo) EXIF() []Field {
+	var fields []Field
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, Field{Name: name, Value: value})
+		}
+	}
+
+	meta := p.MediaMetadata
+	camera := strings.TrimSpace(meta.Photo.CameraMake + " " + meta.Photo.CameraModel)
+	if meta.Video != nil {
+		camera = strings.TrimSpace(meta.Video.CameraMake + " " + meta.Video.CameraModel)
+	}
+	add("Camera", camera)
+	if meta.Photo.FocalLength > 0 {
+		add("Focal length", fmt.Sprintf("%gmm", meta.Photo.FocalLength))
+	}
+	if meta.Photo.ApertureFNumber > 0 {
+		add("Aperture", fmt.Sprintf("f/%g", meta.Photo.ApertureFNumber))
+	}
+	if exposure := time.Duration(meta.Photo.ExposureTime); exposure > 0 {
+		add("Exposure", formatExposure(exposure))
+	}
+	if meta.Photo.IsoEquivalent > 0 {
+		add("ISO", fmt.Sprintf("%d", meta.Photo.IsoEquivalent))
+	}
+	if meta.Width > 0 && meta.Height > 0 {
+		add("Dimensions", fmt.Sprintf("%d × %d", meta.Width, meta.Height))
+	}
+	if !meta.CreationTime.IsZero() {
+		add("Taken", meta.CreationTime.Format("2006-01-02 15:04"))
+	}
+	return fields
+}
+
+// formatExposure format exposure time as photographers do, e.g. 1/250s.
+func formatExposure(d time.Duration) string {
+	if d >= time.Second {
+		return fmt.Sprintf("%gs", d.Seconds())
+	}
+	return fmt.Sprintf("1/%.0fs", math.Round(1/d.Seconds()))
+}
+
+// generator hold state of a single run.
+type generator struct {
+	client    *gphoto.Client
+	opts      Options
+	templates *template.Template
+	report    *Report
+	// previous and current map generated files to the variant they hold, pages map to "".
+	previous map[string]string
+	current  map[string]string
+}
+
+// Generate fetch albums and photos from the client, download their variants and write the site.
+// Reruns are incremental: variants already downloaded are kept, pages are written only if
+// their content changed and files of photos no longer published are removed.
+func Generate(client *gphoto.Client, opts Options) (*Report, error) {
+	if opts.Title == "" {
+		opts.Title = "Albums"
+	}
+	if opts.ThumbSize == (gphoto.ImageSize{}) {
+		opts.ThumbSize = DefaultThumbSize
+	}
+	if opts.ImageSize == (gphoto.ImageSize{}) {
+		opts.ImageSize = DefaultImageSize
+	}
+
+	templates, err := parseTemplates(opts.Templates)
+	if err != nil {
+		logrus.WithError(err).Errorln(templatesErr)
+		return nil, templatesErr
+	}
+
+	g := &generator{
+		client:    client,
+		opts:      opts,
+		templates: templates,
+		report:    new(Report),
+		previous:  readManifest(opts.Dir),
+		current:   make(map[string]string),
+	}
+	if err = g.generate(); err != nil {
+		if err == gphoto.ErrQuotaExceeded {
+			return g.report, err
+		}
+		logrus.WithError(err).Errorln(galleryErr)
+		return g.report, galleryErr
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"albums":     g.report.Albums,
+		"photos":     g.report.Photos,
+		"downloaded": g.report.Downloaded,
+		"written":    g.report.Written,
+		"removed":    g.report.Removed,
+	}).Infoln("gallery generated")
+	return g.report, nil
+}
+
+func (g *generator) generate() error {
+	site, err := g.site()
+	if err != nil {
+		return err
+	}
+
+	for _, album := range site.Albums {
+		for _, photo := range album.Photos {
+			if err = g.variant(photo.ID, photo.Thumb, g.opts.ThumbSize); err != nil {
+				return err
+			}
+			if err = g.variant(photo.ID, photo.Image, g.opts.ImageSize); err != nil {
+				return err
+			}
+			if err = g.page(photo.Page, "photo.html", photo); err != nil {
+				return err
+			}
+		}
+		if err = g.page(album.Page, "album.html", album); err != nil {
+			return err
+		}
+	}
+	if err = g.page("index.html", "index.html", site); err != nil {
+		return err
+	}
+
+	if err = g.removeStale(); err != nil {
+		return err
+	}
+	return g.writeManifest()
+}
+
+// site fetch the published albums and their photos.
+func (g *generator) site() (*Site, error) {
+	albums, err := g.client.GetAlbumList()
+	if err != nil {
+		return nil, err
+	}
+
+	site := &Site{Title: g.opts.Title}
+	for _, googleAlbum := range albums {
+		if len(g.opts.Albums) > 0 && !contains(g.opts.Albums, googleAlbum.ID) {
+			continue
+		}
+		photos, err := g.client.GetPhotoByAlbum(googleAlbum.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		slug := slugify(googleAlbum.Title, googleAlbum.ID)
+		album := &Album{
+			GoogleAlbum: googleAlbum,
+			Page:        path.Join(albumsDir, slug, "index.html"),
+			Site:        site,
+		}
+		for i, googlePhoto := range photos {
+			key := fileKey(googlePhoto.ID)
+			photo := &Photo{
+				GooglePhoto: googlePhoto,
+				Album:       album,
+				Page:        path.Join(albumsDir, slug, key+".html"),
+				Thumb:       path.Join(mediaDir, key+"-thumb.jpg"),
+				Image:       path.Join(mediaDir, key+".jpg"),
+			}
+			if i > 0 {
+				photo.Prev = album.Photos[i-1]
+				album.Photos[i-1].Next = photo
+			}
+			album.Photos = append(album.Photos, photo)
+			if googlePhoto.ID == googleAlbum.CoverPhotoMediaItemID || album.Cover == "" {
+				album.Cover = photo.Thumb
+			}
+		}
+
+		site.Albums = append(site.Albums, album)
+		g.report.Albums++
+		g.report.Photos += len(album.Photos)
+	}
+	return site, nil
+}
+
+// variant download the variant of the media item to name unless it was downloaded before.
+func (g *generator) variant(mediaID, name string, size gphoto.ImageSize) error {
+	spec := fmt.Sprintf("w%d-h%d-c%t", size.Width, size.Height, size.Crop)
+	g.current[name] = spec
+
+	target := filepath.Join(g.opts.Dir, filepath.FromSlash(name))
+	if g.previous[name] == spec {
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	image, err := g.client.OpenImage(mediaID, size)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = image.Close()
+	}()
+
+	part := target + partSuffix
+	file, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, image); err != nil {
+		_ = file.Close()
+		_ = os.Remove(part)
+		return err
+	}
+	if err = file.Close(); err != nil {
+		_ = os.Remove(part)
+		return err
+	}
+	if err = os.Rename(part, target); err != nil {
+		return err
+	}
+
+	g.report.Downloaded++
+	logrus.WithFields(logrus.Fields{"media": mediaID, "path": name}).Debugln("gallery variant downloaded")
+	return nil
+}
+
+// page render the template into name, the file is left untouched if its content is the same.
+func (g *generator) page(name, tmpl string, data interface{}) error {
+	g.current[name] = ""
+
+	var buf bytes.Buffer
+	if err := g.templates.ExecuteTemplate(&buf, tmpl, data); err != nil {
+		return err
+	}
+
+	target := filepath.Join(g.opts.Dir, filepath.FromSlash(name))
+	if existing, err := ioutil.ReadFile(target); err == nil && bytes.Equal(existing, buf.Bytes()) {
+		g.report.Unchanged++
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(target, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	g.report.Written++
+	return nil
+}
+
+// removeStale delete files of the previous run which are not a part of the site anymore.
+func (g *generator) removeStale() error {
+	var stale []string
+	for name := range g.previous {
+		if _, ok := g.current[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+
+	for _, name := range stale {
+		target := filepath.Join(g.opts.Dir, filepath.FromSlash(name))
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// album directories left empty are removed as well, non empty ones fail silently.
+		_ = os.Remove(filepath.Dir(target))
+		g.report.Removed++
+	}
+	return nil
+}
+
+// readManifest read files generated by the previous run, a missing manifest is empty.
+func readManifest(dir string) map[string]string {
+	files := make(map[string]string)
+	data, err := ioutil.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return files
+	}
+	if err = json.Unmarshal(data, &files); err != nil {
+		logrus.WithError(err).Warnln("gallery manifest is ignored")
+		return make(map[string]string)
+	}
+	return files
+}
+
+func (g *generator) writeManifest() error {
+	data, err := json.MarshalIndent(g.current, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(g.opts.Dir, manifestFile), data, 0644)
+}
+
+// relativeRoot return the path from the directory of page to the site root.
+func relativeRoot(page string) string {
+	depth := strings.Count(page, "/")
+	return strings.Repeat("../", depth)
+}
+
+// slugify make a readable and stable directory name of the album.
+func slugify(title, id string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return fileKey(id)
+	}
+	return slug + "-" + fileKey(id)[:8]
+}
+
+// fileKey make a short file name of the media item id.
+func fileKey(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
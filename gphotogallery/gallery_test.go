@@ -0,0 +1,124 @@
+package gphotogallery
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihippik/gphoto"
+	"github.com/ihippik/gphoto/gphototest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFile(t *testing.T, dir, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestGenerate(t *testing.T) {
+	s := gphototest.NewServer()
+	defer s.Close()
+	clientOpts := append(s.ClientOptions(), gphoto.WithRepository(gphoto.NewMemoryRepository(0, 0, 0)))
+	client, err := gphoto.NewGoogleClient(gphototest.ClientID, gphototest.ClientSecret, gphototest.RefreshToken, clientOpts...)
+	require.NoError(t, err)
+	defer client.Close()
+
+	dir, err := ioutil.TempDir("", "gphotogallery")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	holidays := s.AddAlbum(&gphoto.GoogleAlbum{Title: "Summer Holidays!"})
+	work := s.AddAlbum(&gphoto.GoogleAlbum{Title: "Work"})
+	beach := &gphoto.GooglePhoto{Filename: "beach.jpg", Description: "Sea & sand", MimeType: "image/jpeg"}
+	beach.MediaMetadata.Photo = gphoto.PhotoMetadata{
+		CameraMake:      "Canon",
+		CameraModel:     "EOS 5D",
+		FocalLength:     35,
+		ApertureFNumber: 2.8,
+		IsoEquivalent:   200,
+		ExposureTime:    gphoto.ExposureTime(4 * time.Millisecond),
+	}
+	beach = s.AddMediaItem(holidays.ID, beach, []byte("beach"))
+	sunset := s.AddMediaItem(holidays.ID, &gphoto.GooglePhoto{Filename: "sunset.jpg", MimeType: "image/jpeg"}, []byte("sunset"))
+	s.AddMediaItem(work.ID, &gphoto.GooglePhoto{Filename: "desk.jpg", MimeType: "image/jpeg"}, []byte("desk"))
+
+	report, err := Generate(client, Options{Dir: dir, Title: "My photos"})
+	require.NoError(t, err)
+	assert.Equal(t, &Report{Albums: 2, Photos: 3, Downloaded: 6, Written: 6}, report)
+
+	index := readFile(t, dir, "index.html")
+	assert.Contains(t, index, "<title>My photos</title>")
+	albumPage := "albums/" + slugify(holidays.Title, holidays.ID) + "/index.html"
+	assert.True(t, strings.HasPrefix(albumPage, "albums/summer-holidays-"))
+	assert.Contains(t, index, `href="`+albumPage+`"`)
+
+	album := readFile(t, dir, albumPage)
+	assert.Contains(t, album, `src="../../media/`+fileKey(beach.ID)+`-thumb.jpg"`)
+	assert.Contains(t, album, `href="../../index.html"`)
+
+	photo := readFile(t, dir, "albums/"+slugify(holidays.Title, holidays.ID)+"/"+fileKey(beach.ID)+".html")
+	for _, v := range []string{"Canon EOS 5D", "35mm", "f/2.8", "1/250s", "200", "Sea &amp; sand"} {
+		assert.Contains(t, photo, v)
+	}
+	assert.Contains(t, photo, `href="../../albums/`+slugify(holidays.Title, holidays.ID)+"/"+fileKey(sunset.ID)+`.html">next`)
+	assert.Equal(t, "beach", readFile(t, dir, "media/"+fileKey(beach.ID)+".jpg"))
+
+	report, err = Generate(client, Options{Dir: dir, Title: "My photos"})
+	require.NoError(t, err)
+	assert.Equal(t, &Report{Albums: 2, Photos: 3, Unchanged: 6}, report, "rerun is incremental")
+
+	templates, err := ioutil.TempDir("", "gphotogallery-templates")
+	require.NoError(t, err)
+	defer os.RemoveAll(templates)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templates, "index.html"), []byte(`custom {{len .Albums}}`), 0644))
+
+	report, err = Generate(client, Options{Dir: dir, Title: "My photos", Albums: []string{holidays.ID}, Templates: templates})
+	require.NoError(t, err)
+	assert.Equal(t, &Report{Albums: 1, Photos: 2, Written: 1, Unchanged: 3, Removed: 4}, report)
+	assert.Equal(t, "custom 1", readFile(t, dir, "index.html"))
+	_, err = os.Stat(filepath.Join(dir, albumsDir, slugify(work.Title, work.ID)))
+	assert.True(t, os.IsNotExist(err), "files of unpublished albums are removed")
+}
+
+func TestPhoto_EXIF(t *testing.T) {
+	tests := []struct {
+		name string
+		meta gphoto.MediaMetadata
+		want []Field
+	}{
+		{
+			name: "photo",
+			meta: gphoto.MediaMetadata{
+				CreationTime: time.Date(2020, 5, 1, 12, 30, 0, 0, time.UTC),
+				Width:        800,
+				Height:       600,
+				Photo:        gphoto.PhotoMetadata{CameraModel: "Pixel", ExposureTime: gphoto.ExposureTime(2 * time.Second)},
+			},
+			want: []Field{
+				{Name: "Camera", Value: "Pixel"},
+				{Name: "Exposure", Value: "2s"},
+				{Name: "Dimensions", Value: "800 × 600"},
+				{Name: "Taken", Value: "2020-05-01 12:30"},
+			},
+		},
+		{
+			name: "video",
+			meta: gphoto.MediaMetadata{Video: &gphoto.VideoMetadata{CameraMake: "GoPro"}},
+			want: []Field{{Name: "Camera", Value: "GoPro"}},
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			photo := &Photo{GooglePhoto: &gphoto.GooglePhoto{MediaMetadata: tt.meta}}
+			assert.Equal(t, tt.want, photo.EXIF())
+		})
+	}
+}
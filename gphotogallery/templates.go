@@ -0,0 +1,107 @@
+package gphotogallery
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+// defaultTemplates render the gallery, each of them can be replaced by Options.Templates.
+const defaultTemplates = `
+{{define "head"}}<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>
+body{margin:0;font-family:sans-serif;background:#111;color:#eee}
+a{color:#9cf;text-decoration:none}
+header{padding:1em 2em}
+h1{margin:0;font-weight:normal}
+.grid{display:grid;grid-template-columns:repeat(auto-fill,minmax(200px,1fr));gap:8px;padding:0 2em 2em}
+.grid img{width:100%;display:block}
+.grid figcaption{padding:.3em 0}
+.photo{text-align:center;padding:0 2em}
+.photo img{max-width:100%;max-height:80vh}
+nav{display:flex;justify-content:space-between;padding:1em 2em}
+dl{display:grid;grid-template-columns:max-content auto;gap:.3em 1em;max-width:40em;margin:1em auto;text-align:left}
+dt{color:#999}
+</style>{{end}}
+
+{{define "index.html"}}<!DOCTYPE html>
+<html>
+<head>
+<title>{{.Title}}</title>
+{{template "head"}}
+</head>
+<body>
+<header><h1>{{.Title}}</h1></header>
+<div class="grid">
+{{- range .Albums}}
+<figure><a href="{{.Page}}">{{if .Cover}}<img src="{{.Cover}}" alt="{{.Title}}" loading="lazy">{{end}}<figcaption>{{.Title}} ({{len .Photos}})</figcaption></a></figure>
+{{- end}}
+</div>
+</body>
+</html>
+{{end}}
+
+{{define "album.html"}}{{$root := .Root}}<!DOCTYPE html>
+<html>
+<head>
+<title>{{.Title}}</title>
+{{template "head"}}
+</head>
+<body>
+<header><a href="{{$root}}index.html">{{.Site.Title}}</a><h1>{{.Title}}</h1></header>
+<div class="grid">
+{{- range .Photos}}
+<figure><a href="{{$root}}{{.Page}}"><img src="{{$root}}{{.Thumb}}" alt="{{.Filename}}" loading="lazy"></a></figure>
+{{- end}}
+</div>
+</body>
+</html>
+{{end}}
+
+{{define "photo.html"}}{{$root := .Root}}<!DOCTYPE html>
+<html>
+<head>
+<title>{{.Filename}} - {{.Album.Title}}</title>
+{{template "head"}}
+</head>
+<body>
+<nav>
+<span>{{with .Prev}}<a href="{{$root}}{{.Page}}">&larr; previous</a>{{end}}</span>
+<a href="{{$root}}{{.Album.Page}}">{{.Album.Title}}</a>
+<span>{{with .Next}}<a href="{{$root}}{{.Page}}">next &rarr;</a>{{end}}</span>
+</nav>
+<div class="photo">
+<a href="{{$root}}{{.Image}}"><img src="{{$root}}{{.Image}}" alt="{{.Filename}}"></a>
+{{with .Description}}<p>{{.}}</p>{{end}}
+{{with .EXIF}}<dl>
+{{- range .}}
+<dt>{{.Name}}</dt><dd>{{.Value}}</dd>
+{{- end}}
+</dl>{{end}}
+</div>
+</body>
+</html>
+{{end}}
+`
+
+// parseTemplates parse the default templates overridden by *.html files of dir.
+// A file replace the template of its name, templates it defines replace the default ones.
+func parseTemplates(dir string) (*template.Template, error) {
+	templates, err := template.New("gallery").Parse(defaultTemplates)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return templates, nil
+	}
+
+	if _, err = os.Stat(dir); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil || len(files) == 0 {
+		return templates, err
+	}
+	return templates.ParseFiles(files...)
+}
@@ -0,0 +1,78 @@
+package gphotohttp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS describe cross-origin requests allowed by Handler.
+type CORS struct {
+	// AllowedOrigins are origins allowed to read responses, "*" allow any origin.
+	// Credentials are allowed to the listed origins only, never to ones matched by "*".
+	AllowedOrigins []string
+	// AllowedHeaders are request headers allowed in addition to CORS-safelisted ones.
+	AllowedHeaders []string
+	// AllowCredentials let browsers send cookies and authorization headers.
+	AllowCredentials bool
+	// MaxAge is how long browsers cache preflight responses, zero leaves the browser default.
+	MaxAge time.Duration
+}
+
+// match report whether requests from origin are allowed and whether the origin is listed
+// rather than matched by "*".
+func (c *CORS) match(origin string) (allowed, listed bool) {
+	for _, o := range c.AllowedOrigins {
+		if strings.EqualFold(o, origin) {
+			return true, true
+		}
+		if o == "*" {
+			allowed = true
+		}
+	}
+	return allowed, false
+}
+
+// handle set CORS headers of the response and report whether the request was a preflight one
+// and is answered already.
+func (c *CORS) handle(w http.ResponseWriter, r *http.Request) bool {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	allowed, listed := c.match(origin)
+	if origin == "" || !allowed {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	// any origin may read responses without credentials only, it is never reflected.
+	if listed {
+		header.Set("Access-Control-Allow-Origin", origin)
+		if c.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+	} else {
+		header.Set("Access-Control-Allow-Origin", "*")
+	}
+	if !preflight {
+		header.Set("Access-Control-Expose-Headers", "ETag")
+		return false
+	}
+
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", allowedMethods)
+	if len(c.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(c.AllowedHeaders, ", "))
+	}
+	if c.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
@@ -0,0 +1,293 @@
+// Package gphotohttp serve albums and photos of a gphoto.Client as a JSON REST api.
+package gphotohttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ihippik/gphoto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+	allowedMethods  = "GET, HEAD"
+)
+
+// Handler serve the Client as
+//
+//	GET /albums              albums of the account
+//	GET /albums/{id}/photos  photos of the album
+//	GET /photos/{id}         the photo with a fresh base url
+//
+// Lists are paginated with pageSize and pageToken query parameters as in the Library API.
+// List responses carry an ETag of their content, requests with a matching If-None-Match get 304.
+// The photo is never cached as its base url expires.
+// Use http.StripPrefix to serve it under a path prefix.
+type Handler struct {
+	client      *gphoto.Client
+	pageSize    int
+	maxPageSize int
+	cors        *CORS
+}
+
+// Option configure Handler created by NewHandler.
+type Option func(*Handler)
+
+// WithPageSize set the page size used when the request has none and the largest allowed one.
+func WithPageSize(size, max int) Option {
+	return func(h *Handler) {
+		h.pageSize = size
+		h.maxPageSize = max
+	}
+}
+
+// WithCORS allow cross-origin requests described by cors.
+func WithCORS(cors CORS) Option {
+	return func(h *Handler) {
+		h.cors = &cors
+	}
+}
+
+// NewHandler make Handler serving the client.
+func NewHandler(client *gphoto.Client, opts ...Option) *Handler {
+	h := &Handler{
+		client:      client,
+		pageSize:    defaultPageSize,
+		maxPageSize: maxPageSize,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.maxPageSize < h.pageSize {
+		h.maxPageSize = h.pageSize
+	}
+	return h
+}
+
+type albumsResponse struct {
+	Albums        []*gphoto.GoogleAlbum `json:"albums"`
+	NextPageToken string                `json:"nextPageToken,omitempty"`
+}
+
+type photosResponse struct {
+	MediaItems    []*gphoto.GooglePhoto `json:"mediaItems"`
+	NextPageToken string                `json:"nextPageToken,omitempty"`
+}
+
+// ServeHTTP route the request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.cors != nil && h.cors.handle(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", allowedMethods)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	path := strings.Trim(r.URL.EscapedPath(), "/")
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		var err error
+		if parts[i], err = url.PathUnescape(part); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid path")
+			return
+		}
+	}
+
+	switch {
+	case len(parts) == 1 && parts[0] == "albums":
+		h.albums(w, r)
+	case len(parts) == 3 && parts[0] == "albums" && parts[1] != "" && parts[2] == "photos":
+		h.albumPhotos(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "photos" && parts[1] != "":
+		h.photo(w, r, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// albums serve a page of the album list.
+func (h *Handler) albums(w http.ResponseWriter, r *http.Request) {
+	start, size, ok := h.page(w, r)
+	if !ok {
+		return
+	}
+
+	albums, err := h.client.WithContext(r.Context()).GetAlbumList()
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+
+	var res albumsResponse
+	start, end, next := pageBounds(len(albums), start, size)
+	res.Albums, res.NextPageToken = albums[start:end], next
+	if res.Albums == nil {
+		res.Albums = []*gphoto.GoogleAlbum{}
+	}
+	writeJSON(w, r, res)
+}
+
+// albumPhotos serve a page of the album photos.
+func (h *Handler) albumPhotos(w http.ResponseWriter, r *http.Request, albumID string) {
+	start, size, ok := h.page(w, r)
+	if !ok {
+		return
+	}
+
+	photos, err := h.client.WithContext(r.Context()).GetPhotoByAlbum(albumID)
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+
+	var res photosResponse
+	start, end, next := pageBounds(len(photos), start, size)
+	res.MediaItems, res.NextPageToken = photos[start:end], next
+	if res.MediaItems == nil {
+		res.MediaItems = []*gphoto.GooglePhoto{}
+	}
+	writeJSON(w, r, res)
+}
+
+// photo serve a single photo.
+func (h *Handler) photo(w http.ResponseWriter, r *http.Request, mediaID string) {
+	photo, err := h.client.WithContext(r.Context()).GetPhoto(mediaID)
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	writeUncachedJSON(w, photo)
+}
+
+// page parse pageToken and pageSize of the request, the token is an offset in the list.
+func (h *Handler) page(w http.ResponseWriter, r *http.Request) (start, size int, ok bool) {
+	query := r.URL.Query()
+
+	size = h.pageSize
+	if v := query.Get("pageSize"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid pageSize")
+			return 0, 0, false
+		}
+		size = n
+	}
+	if size > h.maxPageSize {
+		size = h.maxPageSize
+	}
+
+	if v := query.Get("pageToken"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid pageToken")
+			return 0, 0, false
+		}
+		start = n
+	}
+	return start, size, true
+}
+
+// pageBounds clamp the page to the list of total items and return the token of the next page.
+func pageBounds(total, start, size int) (int, int, string) {
+	if start > total {
+		start = total
+	}
+	if start+size >= total {
+		return start, total, ""
+	}
+	return start, start + size, strconv.Itoa(start + size)
+}
+
+// writeJSON write v with its ETag, or 304 if the request already has the content.
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, ok := marshalResponse(w, v)
+	if !ok {
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeBody(w, body)
+}
+
+// writeUncachedJSON write v forbidding the client to store it.
+func writeUncachedJSON(w http.ResponseWriter, v interface{}) {
+	body, ok := marshalResponse(w, v)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeBody(w, body)
+}
+
+// marshalResponse encode v, on failure the error response is written.
+func marshalResponse(w http.ResponseWriter, v interface{}) ([]byte, bool) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		logrus.WithError(err).Errorln("marshal response error")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return nil, false
+	}
+	return body, true
+}
+
+// writeBody write the encoded JSON response.
+func writeBody(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)+1))
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// etagMatch report whether If-None-Match header list the etag, weak tags match as well.
+func etagMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeClientError map Client error to the response status.
+func writeClientError(w http.ResponseWriter, err error) {
+	switch err {
+	case gphoto.ErrPhotoNotExists, gphoto.ErrAlbumNotExists:
+		writeError(w, http.StatusNotFound, err.Error())
+	case gphoto.ErrQuotaExceeded:
+		writeError(w, http.StatusTooManyRequests, err.Error())
+	default:
+		writeError(w, http.StatusBadGateway, err.Error())
+	}
+}
+
+// writeError write error in the format of Google APIs.
+func writeError(w http.ResponseWriter, status int, message string) {
+	var buf bytes.Buffer
+	_ = json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    status,
+			"message": message,
+			"status":  strings.ToUpper(strings.Replace(http.StatusText(status), " ", "_", -1)),
+		},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,215 @@
+package gphotohttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ihippik/gphoto"
+	"github.com/ihippik/gphoto/gphototest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHandler(t *testing.T, opts ...Option) (*Handler, *gphototest.Server, func()) {
+	s := gphototest.NewServer()
+	clientOpts := append(s.ClientOptions(), gphoto.WithRepository(gphoto.NewMemoryRepository(0, 0, 0)))
+	client, err := gphoto.NewGoogleClient(gphototest.ClientID, gphototest.ClientSecret, gphototest.RefreshToken, clientOpts...)
+	require.NoError(t, err)
+
+	return NewHandler(client, opts...), s, func() {
+		_ = client.Close()
+		s.Close()
+	}
+}
+
+func serve(h http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	h, s, cleanup := newTestHandler(t, WithPageSize(2, 3))
+	defer cleanup()
+
+	album := s.AddAlbum(&gphoto.GoogleAlbum{Title: "Holidays"})
+	for i := 0; i < 3; i++ {
+		s.AddMediaItem(album.ID, &gphoto.GooglePhoto{Filename: fmt.Sprintf("%d.jpg", i)}, nil)
+	}
+	for i := 0; i < 2; i++ {
+		s.AddAlbum(&gphoto.GoogleAlbum{Title: fmt.Sprintf("album %d", i)})
+	}
+
+	t.Run("albums pages", func(t *testing.T) {
+		var res albumsResponse
+		w := serve(h, "GET", "/albums", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Len(t, res.Albums, 2)
+		assert.Equal(t, "2", res.NextPageToken)
+
+		w = serve(h, "GET", "/albums?pageToken=2&pageSize=10", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		res = albumsResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Len(t, res.Albums, 1)
+		assert.Empty(t, res.NextPageToken)
+
+		w = serve(h, "GET", "/albums?pageToken=10", nil)
+		assert.JSONEq(t, `{"albums":[]}`, w.Body.String())
+	})
+
+	t.Run("album photos", func(t *testing.T) {
+		var res photosResponse
+		w := serve(h, "GET", "/albums/"+album.ID+"/photos?pageSize=5", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Len(t, res.MediaItems, 3, "page size is limited by the max one")
+		assert.Equal(t, "0.jpg", res.MediaItems[0].Filename)
+	})
+
+	t.Run("photo", func(t *testing.T) {
+		id := s.MediaItems()[1].ID
+		var photo gphoto.GooglePhoto
+		w := serve(h, "GET", "/photos/"+id, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &photo))
+		assert.Equal(t, "1.jpg", photo.Filename)
+		assert.Empty(t, w.Header().Get("ETag"), "base url expires, the photo is not cached")
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+		w = serve(h, "GET", "/photos/"+id, http.Header{"If-None-Match": {"*"}})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("etag", func(t *testing.T) {
+		w := serve(h, "GET", "/albums", nil)
+		etag := w.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		w = serve(h, "GET", "/albums", http.Header{"If-None-Match": {`"other", W/` + etag}})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+
+		etag = serve(h, "GET", "/albums?pageToken=2", nil).Header().Get("ETag")
+		s.AddAlbum(&gphoto.GoogleAlbum{Title: "new"})
+		w = serve(h, "GET", "/albums?pageToken=2", http.Header{"If-None-Match": {etag}})
+		assert.Equal(t, http.StatusOK, w.Code, "changed content has a new etag")
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{name: "unknown photo", method: "GET", target: "/photos/missing", status: http.StatusNotFound},
+		{name: "unknown album", method: "GET", target: "/albums/missing/photos", status: http.StatusNotFound},
+		{name: "unknown path", method: "GET", target: "/albums/" + album.ID, status: http.StatusNotFound},
+		{name: "invalid page token", method: "GET", target: "/albums?pageToken=x", status: http.StatusBadRequest},
+		{name: "invalid page size", method: "GET", target: "/albums?pageSize=0", status: http.StatusBadRequest},
+		{name: "method not allowed", method: "POST", target: "/albums", status: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(h, tt.method, tt.target, nil)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), fmt.Sprintf(`"code":%d`, tt.status))
+		})
+	}
+}
+
+func TestHandler_concurrentRequests(t *testing.T) {
+	s := gphototest.NewServer()
+	defer s.Close()
+	clientOpts := append(s.ClientOptions(),
+		gphoto.WithRepository(gphoto.NewMemoryRepository(0, 0, 0)),
+		gphoto.WithCacheTTL(time.Minute, time.Minute),
+	)
+	client, err := gphoto.NewGoogleClient(gphototest.ClientID, gphototest.ClientSecret, gphototest.RefreshToken, clientOpts...)
+	require.NoError(t, err)
+	defer client.Close()
+
+	album := s.AddAlbum(&gphoto.GoogleAlbum{Title: "Holidays"})
+	photo := s.AddMediaItem(album.ID, &gphoto.GooglePhoto{Filename: "0.jpg"}, nil)
+	handlers := []*Handler{NewHandler(client), NewHandler(client)}
+	require.Equal(t, http.StatusOK, serve(handlers[0], "GET", "/albums", nil).Code)
+	s.ExpireTokens()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(h *Handler) {
+			defer wg.Done()
+			for _, target := range []string{"/albums", "/albums/" + album.ID + "/photos", "/photos/" + photo.ID} {
+				assert.Equal(t, http.StatusOK, serve(h, "GET", target, nil).Code, target)
+			}
+		}(handlers[i%2])
+	}
+	wg.Wait()
+
+	var refreshes int
+	for _, req := range s.Requests() {
+		if req == "POST /token" {
+			refreshes++
+		}
+	}
+	assert.Equal(t, 2, refreshes, "expired token is refreshed once")
+}
+
+func TestHandler_CORS(t *testing.T) {
+	h, _, cleanup := newTestHandler(t, WithCORS(CORS{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedHeaders: []string{"If-None-Match"},
+		MaxAge:         time.Hour,
+	}))
+	defer cleanup()
+
+	preflight := http.Header{
+		"Origin":                        {"https://app.example.com"},
+		"Access-Control-Request-Method": {"GET"},
+	}
+	w := serve(h, "OPTIONS", "/albums", preflight)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "If-None-Match", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+
+	preflight.Set("Origin", "https://evil.example.com")
+	w = serve(h, "OPTIONS", "/albums", preflight)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = serve(h, "GET", "/albums", http.Header{"Origin": {"https://app.example.com"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "ETag", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+}
+
+func TestHandler_CORSWildcard(t *testing.T) {
+	h, _, cleanup := newTestHandler(t, WithCORS(CORS{
+		AllowedOrigins:   []string{"*", "https://app.example.com"},
+		AllowCredentials: true,
+	}))
+	defer cleanup()
+
+	w := serve(h, "GET", "/albums", http.Header{"Origin": {"https://evil.example.com"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"), "origin matched by wildcard is not reflected")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+
+	w = serve(h, "GET", "/albums", http.Header{"Origin": {"https://app.example.com"}})
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"), "listed origin get credentials")
+}
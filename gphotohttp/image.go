@@ -0,0 +1,265 @@
+package gphotohttp
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ihippik/gphoto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxDimension = 2048
+	defaultImageMaxAge  = 24 * time.Hour
+	imageSuffix         = ".img"
+	partSuffix          = ".part"
+)
+
+// ImageProxy serve /img/{mediaID}?w=&h=&crop= resolving a fresh base url through the Client,
+// so pages never embed base urls expiring after an hour. Google renders the variant,
+// w and h bound it keeping the aspect ratio, crop=1 fill exactly w x h.
+// Requests without w and h get the image bounded by the max dimension.
+// Variants are cached on disk, the least recently served are removed above the cache size.
+type ImageProxy struct {
+	client       *gphoto.Client
+	dir          string
+	cacheBytes   int64
+	maxDimension int
+	maxAge       time.Duration
+	// evictMu keep a single eviction running.
+	evictMu sync.Mutex
+}
+
+// ProxyOption configure ImageProxy created by NewImageProxy.
+type ProxyOption func(*ImageProxy)
+
+// WithCacheSize bound bytes of cached variants, zero disables the limit.
+func WithCacheSize(bytes int64) ProxyOption {
+	return func(p *ImageProxy) {
+		p.cacheBytes = bytes
+	}
+}
+
+// WithMaxDimension limit width and height of served variants, larger ones are reduced to it.
+func WithMaxDimension(pixels int) ProxyOption {
+	return func(p *ImageProxy) {
+		p.maxDimension = pixels
+	}
+}
+
+// WithMaxAge set how long browsers and CDNs may cache served images.
+func WithMaxAge(maxAge time.Duration) ProxyOption {
+	return func(p *ImageProxy) {
+		p.maxAge = maxAge
+	}
+}
+
+// NewImageProxy make ImageProxy caching variants in dir, the directory is created if needed.
+func NewImageProxy(client *gphoto.Client, dir string, opts ...ProxyOption) (*ImageProxy, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	p := &ImageProxy{
+		client:       client,
+		dir:          dir,
+		maxDimension: defaultMaxDimension,
+		maxAge:       defaultImageMaxAge,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+// ServeHTTP serve the image variant from the disk cache or stream it from Google caching it.
+func (p *ImageProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", allowedMethods)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	if len(parts) != 2 || parts[0] != "img" || parts[1] == "" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	mediaID, err := url.PathUnescape(parts[1])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid path")
+		return
+	}
+	size, err := p.size(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key := variantKey(mediaID, size)
+	header := w.Header()
+	header.Set("ETag", `"`+key+`"`)
+	header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(p.maxAge/time.Second)))
+	if etagMatch(r.Header.Get("If-None-Match"), `"`+key+`"`) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	path := filepath.Join(p.dir, key+imageSuffix)
+	if file, err := os.Open(path); err == nil {
+		defer func() {
+			_ = file.Close()
+		}()
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+		http.ServeContent(w, r, "", time.Time{}, file)
+		return
+	}
+
+	image, err := p.client.WithContext(r.Context()).OpenImage(mediaID, size)
+	if err != nil {
+		header.Del("ETag")
+		header.Del("Cache-Control")
+		writeClientError(w, err)
+		return
+	}
+	defer func() {
+		_ = image.Close()
+	}()
+
+	if err = p.stream(w, image, path); err != nil {
+		logrus.WithError(err).WithField("media", mediaID).Warnln("image is not cached")
+		return
+	}
+	p.evict()
+}
+
+// size parse the requested variant.
+func (p *ImageProxy) size(query url.Values) (gphoto.ImageSize, error) {
+	var (
+		size gphoto.ImageSize
+		err  error
+	)
+	if size.Width, err = p.dimension(query, "w"); err != nil {
+		return size, err
+	}
+	if size.Height, err = p.dimension(query, "h"); err != nil {
+		return size, err
+	}
+	if v := query.Get("crop"); v != "" {
+		if size.Crop, err = strconv.ParseBool(v); err != nil {
+			return size, errors.New("invalid crop")
+		}
+	}
+
+	if size.Crop && (size.Width == 0 || size.Height == 0) {
+		return size, errors.New("crop requires w and h")
+	}
+	if size.Width == 0 && size.Height == 0 {
+		size.Width, size.Height = p.maxDimension, p.maxDimension
+	}
+	return size, nil
+}
+
+// dimension parse width or height, values above the max dimension are reduced to it.
+func (p *ImageProxy) dimension(query url.Values, name string) (int, error) {
+	v := query.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid " + name)
+	}
+	if p.maxDimension > 0 && n > p.maxDimension {
+		n = p.maxDimension
+	}
+	return n, nil
+}
+
+// stream copy the image to the response and into the cache file at path.
+// The file is kept only if the whole image is copied.
+func (p *ImageProxy) stream(w http.ResponseWriter, image io.Reader, path string) error {
+	buffered := bufio.NewReader(image)
+	head, _ := buffered.Peek(512)
+	w.Header().Set("Content-Type", http.DetectContentType(head))
+
+	part, err := ioutil.TempFile(p.dir, filepath.Base(path)+"-*"+partSuffix)
+	if err != nil {
+		_, _ = io.Copy(w, buffered)
+		return err
+	}
+	defer func() {
+		_ = part.Close()
+		_ = os.Remove(part.Name())
+	}()
+
+	w.WriteHeader(http.StatusOK)
+	if _, err = io.Copy(w, io.TeeReader(buffered, part)); err != nil {
+		return err
+	}
+	if err = part.Close(); err != nil {
+		return err
+	}
+	return os.Rename(part.Name(), path)
+}
+
+// evict remove the least recently served variants above the cache size.
+func (p *ImageProxy) evict() {
+	if p.cacheBytes <= 0 {
+		return
+	}
+	p.evictMu.Lock()
+	defer p.evictMu.Unlock()
+
+	infos, err := ioutil.ReadDir(p.dir)
+	if err != nil {
+		logrus.WithError(err).Warnln("image cache is not evicted")
+		return
+	}
+
+	var (
+		files []os.FileInfo
+		total int64
+	)
+	for _, info := range infos {
+		if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), imageSuffix) {
+			files = append(files, info)
+			total += info.Size()
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, info := range files {
+		if total <= p.cacheBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(p.dir, info.Name())); err != nil && !os.IsNotExist(err) {
+			logrus.WithError(err).Warnln("cached image is not removed")
+			continue
+		}
+		total -= info.Size()
+		logrus.WithField("file", info.Name()).Debugln("cached image evicted")
+	}
+}
+
+// variantKey make a file name safe key of the media item variant.
+func variantKey(mediaID string, size gphoto.ImageSize) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%t", mediaID, size.Width, size.Height, size.Crop)))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,86 @@
+package gphotohttp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihippik/gphoto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contentRequests filter requests of the media content.
+func contentRequests(requests []string) []string {
+	var content []string
+	for _, req := range requests {
+		if strings.HasPrefix(req, "GET /content/") {
+			content = append(content, req)
+		}
+	}
+	return content
+}
+
+func TestImageProxy(t *testing.T) {
+	h, s, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "gphotohttp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte("x"), 100)...)
+	photo := s.AddMediaItem("", &gphoto.GooglePhoto{Filename: "a.png", MimeType: "image/png"}, png)
+	proxy, err := NewImageProxy(h.client, dir, WithCacheSize(150), WithMaxDimension(1000), WithMaxAge(time.Hour))
+	require.NoError(t, err)
+
+	target := "/img/" + photo.ID + "?w=200&h=100&crop=1"
+	w := serve(proxy, "GET", target, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, png, w.Body.Bytes())
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, []string{"GET /content/" + photo.ID + "=w200-h100-c"}, contentRequests(s.Requests()))
+
+	w = serve(proxy, "GET", target, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, png, w.Body.Bytes())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Len(t, contentRequests(s.Requests()), 1, "variant is served from disk")
+
+	w = serve(proxy, "GET", target, http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = serve(proxy, "GET", "/img/"+photo.ID+"?w=5000", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, contentRequests(s.Requests()), "GET /content/"+photo.ID+"=w1000", "size is limited")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+imageSuffix))
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "least recently served variant is evicted above the cache size")
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{name: "unknown photo", target: "/img/missing", status: http.StatusNotFound},
+		{name: "unknown path", target: "/photos/" + photo.ID, status: http.StatusNotFound},
+		{name: "invalid width", target: "/img/" + photo.ID + "?w=x", status: http.StatusBadRequest},
+		{name: "crop without height", target: "/img/" + photo.ID + "?w=10&crop=true", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(proxy, "GET", tt.target, nil)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Empty(t, w.Header().Get("Cache-Control"), "errors are not cached")
+		})
+	}
+}
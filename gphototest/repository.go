@@ -0,0 +1,212 @@
+// Package gphototest provide helpers for testing code built on the gphoto package.
+package gphototest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ihippik/gphoto"
+	"github.com/stretchr/testify/assert"
+)
+
+// RepositoryFactory make an empty repository for a single suite test.
+// cleanup, if not nil, is called after the repository is closed.
+type RepositoryFactory func(t *testing.T) (repo gphoto.Repository, cleanup func())
+
+// RunRepositorySuite check that repositories made by factory follow the gphoto.Repository contract.
+func RunRepositorySuite(t *testing.T, factory RepositoryFactory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo gphoto.Repository)
+	}{
+		{name: "save and list photos", test: testSaveListPhotos},
+		{name: "missing album", test: testMissingAlbum},
+		{name: "empty album", test: testEmptyAlbum},
+		{name: "truncate album", test: testTruncateAlbum},
+		{name: "update photo", test: testUpdatePhoto},
+		{name: "save and get albums", test: testAlbums},
+		{name: "concurrency", test: testConcurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, cleanup := factory(t)
+			if cleanup != nil {
+				defer cleanup()
+			}
+			tt.test(t, repo)
+			assert.NoError(t, repo.Close(), "close")
+		})
+	}
+
+	t.Run("close", func(t *testing.T) {
+		repo, cleanup := factory(t)
+		if cleanup != nil {
+			defer cleanup()
+		}
+		assert.NoError(t, repo.SavePhotos("album", photos("a")))
+		assert.NoError(t, repo.SaveAlbums([]*gphoto.GoogleAlbum{{ID: "album"}}))
+		assert.NoError(t, repo.Close())
+
+		_, err := repo.ListPhotos("album")
+		assert.Error(t, err, "closed repository does not serve cached photos")
+		_, err = repo.GetAlbum("album")
+		assert.Error(t, err, "closed repository does not serve cached albums")
+	})
+}
+
+func testSaveListPhotos(t *testing.T, repo gphoto.Repository) {
+	assert.NoError(t, repo.SavePhotos("album", photos("b", "a")))
+	assert.NoError(t, repo.SavePhotos("album", photos("c")))
+	assert.NoError(t, repo.SavePhotos("other", photos("d")))
+
+	got, err := repo.ListPhotos("album")
+	assert.NoError(t, err)
+	assert.Equal(t, photos("b", "a", "c"), got, "photos are appended in the saved order")
+
+	got, err = repo.ListPhotos("other")
+	assert.NoError(t, err)
+	assert.Equal(t, photos("d"), got)
+
+	var ids []string
+	for i := 0; i < 12; i++ {
+		ids = append(ids, fmt.Sprint(i))
+	}
+	assert.NoError(t, repo.SavePhotos("long", photos(ids...)))
+	got, err = repo.ListPhotos("long")
+	assert.NoError(t, err)
+	assert.Equal(t, photos(ids...), got, "order does not depend on the number of photos")
+}
+
+func testMissingAlbum(t *testing.T, repo gphoto.Repository) {
+	_, err := repo.ListPhotos("missing")
+	assert.Equal(t, gphoto.ErrAlbumNotExists, err)
+
+	_, err = repo.GetAlbum("missing")
+	assert.Equal(t, gphoto.ErrAlbumNotExists, err)
+
+	assert.NoError(t, repo.TruncateAlbum("missing"))
+}
+
+func testEmptyAlbum(t *testing.T, repo gphoto.Repository) {
+	assert.NoError(t, repo.SavePhotos("empty", nil))
+
+	got, err := repo.ListPhotos("empty")
+	assert.NoError(t, err, "album saved without photos exists")
+	assert.Empty(t, got)
+
+	assert.NoError(t, repo.TruncateAlbum("empty"))
+	_, err = repo.ListPhotos("empty")
+	assert.Equal(t, gphoto.ErrAlbumNotExists, err)
+}
+
+func testTruncateAlbum(t *testing.T, repo gphoto.Repository) {
+	assert.NoError(t, repo.SavePhotos("album", photos("a", "b")))
+	assert.NoError(t, repo.SavePhotos("other", photos("b")))
+	assert.NoError(t, repo.TruncateAlbum("album"))
+
+	_, err := repo.ListPhotos("album")
+	assert.Equal(t, gphoto.ErrAlbumNotExists, err)
+
+	got, err := repo.ListPhotos("other")
+	assert.NoError(t, err)
+	assert.Equal(t, photos("b"), got, "other albums are kept")
+
+	assert.NoError(t, repo.SavePhotos("album", photos("c", "a")))
+	assert.NoError(t, repo.SavePhotos("album", photos("b")))
+	got, err = repo.ListPhotos("album")
+	assert.NoError(t, err)
+	assert.Equal(t, photos("c", "a", "b"), got, "photos saved after truncate are ordered from scratch")
+}
+
+func testUpdatePhoto(t *testing.T, repo gphoto.Repository) {
+	assert.NoError(t, repo.SavePhotos("album", photos("a", "b", "c")))
+	assert.NoError(t, repo.SavePhotos("other", photos("b")))
+
+	updated := &gphoto.GooglePhoto{ID: "b", Filename: "b.jpg", Description: "updated"}
+	assert.NoError(t, repo.UpdatePhoto(updated))
+
+	got, err := repo.ListPhotos("album")
+	assert.NoError(t, err)
+	assert.Equal(t, []*gphoto.GooglePhoto{photos("a")[0], updated, photos("c")[0]}, got, "updated photo keep its position")
+
+	got, err = repo.ListPhotos("other")
+	assert.NoError(t, err)
+	assert.Equal(t, []*gphoto.GooglePhoto{updated}, got)
+
+	assert.NoError(t, repo.UpdatePhoto(&gphoto.GooglePhoto{ID: "missing"}), "missing photo is ignored")
+}
+
+func testAlbums(t *testing.T, repo gphoto.Repository) {
+	first := &gphoto.GoogleAlbum{ID: "first", Title: "First"}
+	second := &gphoto.GoogleAlbum{ID: "second", Title: "Second"}
+	assert.NoError(t, repo.SaveAlbums([]*gphoto.GoogleAlbum{first, second}))
+
+	renamed := &gphoto.GoogleAlbum{
+		ID:        "first",
+		Title:     "Renamed",
+		ShareInfo: &gphoto.ShareInfo{ShareToken: "token", IsJoined: true},
+	}
+	assert.NoError(t, repo.SaveAlbums([]*gphoto.GoogleAlbum{renamed}))
+
+	got, err := repo.GetAlbum("first")
+	assert.NoError(t, err)
+	assert.Equal(t, renamed, got, "saved album replace the previous version")
+
+	albums, err := repo.ListAlbums()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []*gphoto.GoogleAlbum{renamed, second}, albums)
+}
+
+func testConcurrency(t *testing.T, repo gphoto.Repository) {
+	const (
+		workers = 8
+		rounds  = 10
+	)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			album := fmt.Sprintf("album%d", w)
+			for i := 0; i < rounds; i++ {
+				assert.NoError(t, repo.SavePhotos(album, photos(fmt.Sprintf("%s-%d", album, i))))
+				_, err := repo.ListPhotos(album)
+				assert.NoError(t, err)
+				assert.NoError(t, repo.SaveAlbums([]*gphoto.GoogleAlbum{{ID: album, Title: fmt.Sprint(i)}}))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w := 0; w < workers; w++ {
+		album := fmt.Sprintf("album%d", w)
+		var want []string
+		for i := 0; i < rounds; i++ {
+			want = append(want, fmt.Sprintf("%s-%d", album, i))
+		}
+		got, err := repo.ListPhotos(album)
+		assert.NoError(t, err)
+		assert.Equal(t, photos(want...), got, "photos of every album keep the saved order")
+	}
+	albums, err := repo.ListAlbums()
+	assert.NoError(t, err)
+	assert.Len(t, albums, workers)
+}
+
+// photos make photos with given ids.
+func photos(ids ...string) []*gphoto.GooglePhoto {
+	photos := make([]*gphoto.GooglePhoto, 0, len(ids))
+	for _, id := range ids {
+		photos = append(photos, &gphoto.GooglePhoto{
+			ID:       id,
+			Filename: id + ".jpg",
+			MimeType: "image/jpeg",
+			BaseURL:  "http://photo/" + id,
+		})
+	}
+	return photos
+}
@@ -0,0 +1,558 @@
+package gphototest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ihippik/gphoto"
+)
+
+// Credentials accepted by Server unless changed.
+const (
+	ClientID     = "test-client-id"
+	ClientSecret = "test-client-secret"
+	RefreshToken = "test-refresh-token"
+	AuthCode     = "test-auth-code"
+)
+
+// Server is a stateful in-memory fake of the Google Photos Library API and the OAuth token endpoint.
+//
+// Supported endpoints: albums list, get and title patch, mediaItems list, get, search
+// (album, date and media type filters) and description patch, uploads and mediaItems:batchCreate.
+// Media content is served from item base urls with Range support.
+type Server struct {
+	*httptest.Server
+
+	// PageSize limit items of every page, zero means the page size of the request.
+	PageSize int
+
+	mu           sync.Mutex
+	clientID     string
+	clientSecret string
+	refreshToken string
+	accessTokens map[string]bool
+	tokenSeq     int
+	albums       []*gphoto.GoogleAlbum
+	items        []*gphoto.GooglePhoto
+	albumItems   map[string][]string
+	content      map[string][]byte
+	uploads      map[string]upload
+	failures     []*failure
+	requests     []string
+}
+
+type upload struct {
+	fileName string
+	content  []byte
+}
+
+// failure is an error response injected into matching requests.
+type failure struct {
+	path   string
+	status int
+	times  int
+}
+
+// NewServer start fake server accepting ClientID, ClientSecret, RefreshToken and AuthCode.
+// Close must be called when the server is not needed anymore.
+func NewServer() *Server {
+	s := &Server{
+		clientID:     ClientID,
+		clientSecret: ClientSecret,
+		refreshToken: RefreshToken,
+		accessTokens: make(map[string]bool),
+		albumItems:   make(map[string][]string),
+		content:      make(map[string][]byte),
+		uploads:      make(map[string]upload),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// TokenURL return url of the OAuth token endpoint.
+func (s *Server) TokenURL() string {
+	return s.URL + "/token"
+}
+
+// ClientOptions return options pointing gphoto.Client to the server.
+func (s *Server) ClientOptions() []gphoto.Option {
+	return []gphoto.Option{gphoto.WithEndpoints(s.URL, s.TokenURL())}
+}
+
+// AddAlbum store album, its id is generated when empty.
+func (s *Server) AddAlbum(album *gphoto.GoogleAlbum) *gphoto.GoogleAlbum {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if album.ID == "" {
+		album.ID = fmt.Sprintf("album-%d", len(s.albums)+1)
+	}
+	if album.ProductURL == "" {
+		album.ProductURL = s.URL + "/lr/album/" + album.ID
+	}
+	s.albums = append(s.albums, album)
+	return album
+}
+
+// AddMediaItem store media item with its content and add it to the album when albumID is not empty.
+// Id, base url and product url are generated when empty.
+func (s *Server) AddMediaItem(albumID string, item *gphoto.GooglePhoto, content []byte) *gphoto.GooglePhoto {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addMediaItem(albumID, item, content)
+}
+
+func (s *Server) addMediaItem(albumID string, item *gphoto.GooglePhoto, content []byte) *gphoto.GooglePhoto {
+	if item.ID == "" {
+		item.ID = fmt.Sprintf("media-%d", len(s.items)+1)
+	}
+	if item.BaseURL == "" {
+		item.BaseURL = s.URL + "/content/" + item.ID
+	}
+	if item.ProductURL == "" {
+		item.ProductURL = s.URL + "/lr/photo/" + item.ID
+	}
+	if item.MediaMetadata.CreationTime.IsZero() {
+		item.MediaMetadata.CreationTime = time.Now().UTC().Truncate(time.Second)
+	}
+	s.items = append(s.items, item)
+	s.content[item.ID] = content
+	if albumID != "" {
+		s.albumItems[albumID] = append(s.albumItems[albumID], item.ID)
+		for _, album := range s.albums {
+			if album.ID == albumID {
+				album.MediaItemsCount = strconv.Itoa(len(s.albumItems[albumID]))
+			}
+		}
+	}
+	return item
+}
+
+// Album return stored album or nil.
+func (s *Server) Album(id string) *gphoto.GoogleAlbum {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.album(id)
+}
+
+// MediaItem return stored media item or nil.
+func (s *Server) MediaItem(id string) *gphoto.GooglePhoto {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.mediaItem(id)
+}
+
+// MediaItems return all stored media items.
+func (s *Server) MediaItems() []*gphoto.GooglePhoto {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*gphoto.GooglePhoto(nil), s.items...)
+}
+
+// ExpireTokens invalidate issued access tokens, the next request with one is answered with 401.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accessTokens = make(map[string]bool)
+}
+
+// Fail answer the next times requests whose path start with pathPrefix with status,
+// 429 responses have Retry-After header. Empty prefix match all requests.
+func (s *Server) Fail(pathPrefix string, status, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, &failure{path: pathPrefix, status: status, times: times})
+}
+
+// Requests return "METHOD path" of every received request.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := r.URL.EscapedPath()
+	s.requests = append(s.requests, r.Method+" "+path)
+
+	for _, f := range s.failures {
+		if f.times > 0 && strings.HasPrefix(path, f.path) {
+			f.times--
+			if f.status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			writeError(w, f.status, "injected failure")
+			return
+		}
+	}
+
+	switch {
+	case path == "/token":
+		s.token(w, r)
+	case strings.HasPrefix(path, "/content/"):
+		s.serveContent(w, r, strings.TrimPrefix(path, "/content/"))
+	case strings.HasPrefix(path, "/v1/"):
+		if !s.accessTokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+			writeError(w, http.StatusUnauthorized, "invalid access token")
+			return
+		}
+		s.library(w, r, strings.TrimPrefix(path, "/v1/"))
+	default:
+		writeError(w, http.StatusNotFound, "unknown path "+path)
+	}
+}
+
+// token issue access tokens for the refresh token or the authorization code.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if r.FormValue("client_id") != s.clientID || r.FormValue("client_secret") != s.clientSecret {
+		writeError(w, http.StatusUnauthorized, "invalid client")
+		return
+	}
+
+	switch r.FormValue("grant_type") {
+	case "refresh_token":
+		if r.FormValue("refresh_token") != s.refreshToken {
+			writeError(w, http.StatusBadRequest, "invalid refresh token")
+			return
+		}
+	case "authorization_code":
+		if r.FormValue("code") != AuthCode {
+			writeError(w, http.StatusBadRequest, "invalid authorization code")
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported grant type")
+		return
+	}
+
+	s.tokenSeq++
+	accessToken := fmt.Sprintf("access-token-%d", s.tokenSeq)
+	s.accessTokens[accessToken] = true
+	writeJSON(w, gphoto.Token{
+		AccessToken:  accessToken,
+		RefreshToken: s.refreshToken,
+		ExpiresIn:    3600,
+		Scope:        gphoto.ScopeLibrary,
+		TokenType:    "Bearer",
+	})
+}
+
+// serveContent serve media content, base url suffixes like =d or =w100-h100 are ignored.
+func (s *Server) serveContent(w http.ResponseWriter, r *http.Request, id string) {
+	if i := strings.Index(id, "="); i >= 0 {
+		id = id[:i]
+	}
+	content, ok := s.content[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "media item not found")
+		return
+	}
+	http.ServeContent(w, r, id, time.Time{}, bytes.NewReader(content))
+}
+
+// library route Library API requests, path is relative to /v1/.
+func (s *Server) library(w http.ResponseWriter, r *http.Request, path string) {
+	switch {
+	case path == "albums" && r.Method == http.MethodGet:
+		albums := s.albums
+		page, next := s.page(len(albums), r.URL.Query())
+		writeJSON(w, map[string]interface{}{"albums": albums[page[0]:page[1]], "nextPageToken": next})
+	case strings.HasPrefix(path, "albums/"):
+		id, _ := url.PathUnescape(strings.TrimPrefix(path, "albums/"))
+		album := s.album(id)
+		if album == nil {
+			writeError(w, http.StatusNotFound, "album not found")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, album)
+		case http.MethodPatch:
+			var patch gphoto.GoogleAlbum
+			if !readJSON(w, r, &patch) {
+				return
+			}
+			if r.URL.Query().Get("updateMask") == "title" {
+				album.Title = patch.Title
+			}
+			writeJSON(w, album)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	case path == "mediaItems:search" && r.Method == http.MethodPost:
+		s.search(w, r)
+	case path == "mediaItems" && r.Method == http.MethodGet:
+		items := s.items
+		page, next := s.page(len(items), r.URL.Query())
+		writeJSON(w, map[string]interface{}{"mediaItems": items[page[0]:page[1]], "nextPageToken": next})
+	case path == "mediaItems:batchCreate" && r.Method == http.MethodPost:
+		s.batchCreate(w, r)
+	case strings.HasPrefix(path, "mediaItems/"):
+		id, _ := url.PathUnescape(strings.TrimPrefix(path, "mediaItems/"))
+		item := s.mediaItem(id)
+		if item == nil {
+			writeError(w, http.StatusNotFound, "media item not found")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, item)
+		case http.MethodPatch:
+			var patch gphoto.GooglePhoto
+			if !readJSON(w, r, &patch) {
+				return
+			}
+			if r.URL.Query().Get("updateMask") == "description" {
+				item.Description = patch.Description
+			}
+			writeJSON(w, item)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	case path == "uploads" && r.Method == http.MethodPost:
+		content, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		token := fmt.Sprintf("upload-token-%d", len(s.uploads)+1)
+		s.uploads[token] = upload{fileName: r.Header.Get("X-Goog-Upload-File-Name"), content: content}
+		_, _ = w.Write([]byte(token))
+	default:
+		writeError(w, http.StatusNotFound, "unknown path /v1/"+path)
+	}
+}
+
+// searchRequest is a mediaItems:search request, the client may send it as JSON or as a form.
+type searchRequest struct {
+	AlbumID   string `json:"albumId"`
+	PageSize  int    `json:"pageSize"`
+	PageToken string `json:"pageToken"`
+	Filters   *struct {
+		DateFilter *struct {
+			Ranges []struct {
+				StartDate date `json:"startDate"`
+				EndDate   date `json:"endDate"`
+			} `json:"ranges"`
+		} `json:"dateFilter"`
+		MediaTypeFilter *struct {
+			MediaTypes []string `json:"mediaTypes"`
+		} `json:"mediaTypeFilter"`
+	} `json:"filters"`
+}
+
+type date struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day"`
+}
+
+func (d date) time() time.Time {
+	return time.Date(d.Year, time.Month(d.Month), d.Day, 0, 0, 0, 0, time.UTC)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	var search searchRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if !readJSON(w, r, &search) {
+			return
+		}
+	} else {
+		search.AlbumID = r.FormValue("albumId")
+		search.PageSize, _ = strconv.Atoi(r.FormValue("pageSize"))
+		search.PageToken = r.FormValue("pageToken")
+	}
+
+	var items []*gphoto.GooglePhoto
+	if search.AlbumID != "" {
+		if s.album(search.AlbumID) == nil {
+			writeError(w, http.StatusNotFound, "album not found")
+			return
+		}
+		for _, id := range s.albumItems[search.AlbumID] {
+			items = append(items, s.mediaItem(id))
+		}
+	} else {
+		for _, item := range s.items {
+			if search.matches(item) {
+				items = append(items, item)
+			}
+		}
+	}
+
+	query := url.Values{}
+	query.Set("pageSize", strconv.Itoa(search.PageSize))
+	query.Set("pageToken", search.PageToken)
+	page, next := s.page(len(items), query)
+	writeJSON(w, map[string]interface{}{"mediaItems": items[page[0]:page[1]], "nextPageToken": next})
+}
+
+// matches check media item against date and media type filters.
+func (r *searchRequest) matches(item *gphoto.GooglePhoto) bool {
+	if r.Filters == nil {
+		return true
+	}
+	if f := r.Filters.MediaTypeFilter; f != nil && len(f.MediaTypes) > 0 {
+		switch f.MediaTypes[0] {
+		case gphoto.MediaTypePhoto:
+			if item.IsVideo() {
+				return false
+			}
+		case gphoto.MediaTypeVideo:
+			if !item.IsVideo() {
+				return false
+			}
+		}
+	}
+	if f := r.Filters.DateFilter; f != nil && len(f.Ranges) > 0 {
+		created := item.MediaMetadata.CreationTime
+		for _, rng := range f.Ranges {
+			if !created.Before(rng.StartDate.time()) && created.Before(rng.EndDate.time().AddDate(0, 0, 1)) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (s *Server) batchCreate(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		AlbumID       string `json:"albumId"`
+		NewMediaItems []struct {
+			Description     string `json:"description"`
+			SimpleMediaItem struct {
+				FileName    string `json:"fileName"`
+				UploadToken string `json:"uploadToken"`
+			} `json:"simpleMediaItem"`
+		} `json:"newMediaItems"`
+	}
+	if !readJSON(w, r, &request) {
+		return
+	}
+	if request.AlbumID != "" && s.album(request.AlbumID) == nil {
+		writeError(w, http.StatusNotFound, "album not found")
+		return
+	}
+
+	type status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	type result struct {
+		UploadToken string              `json:"uploadToken"`
+		Status      status              `json:"status"`
+		MediaItem   *gphoto.GooglePhoto `json:"mediaItem,omitempty"`
+	}
+	var results []result
+	for _, newItem := range request.NewMediaItems {
+		token := newItem.SimpleMediaItem.UploadToken
+		up, ok := s.uploads[token]
+		if !ok {
+			results = append(results, result{UploadToken: token, Status: status{Code: 3, Message: "invalid upload token"}})
+			continue
+		}
+		delete(s.uploads, token)
+
+		fileName := newItem.SimpleMediaItem.FileName
+		if fileName == "" {
+			fileName = up.fileName
+		}
+		item := s.addMediaItem(request.AlbumID, &gphoto.GooglePhoto{
+			Description: newItem.Description,
+			Filename:    fileName,
+			MimeType:    http.DetectContentType(up.content),
+		}, up.content)
+		results = append(results, result{UploadToken: token, Status: status{Message: "Success"}, MediaItem: item})
+	}
+	writeJSON(w, map[string]interface{}{"newMediaItemResults": results})
+}
+
+// page return bounds of the requested page and the token of the next one.
+func (s *Server) page(total int, query url.Values) ([2]int, string) {
+	start, _ := strconv.Atoi(query.Get("pageToken"))
+	if start < 0 || start > total {
+		start = total
+	}
+	size, _ := strconv.Atoi(query.Get("pageSize"))
+	if s.PageSize > 0 && (size <= 0 || size > s.PageSize) {
+		size = s.PageSize
+	}
+
+	end := total
+	if size > 0 && start+size < total {
+		end = start + size
+	}
+	var next string
+	if end < total {
+		next = strconv.Itoa(end)
+	}
+	return [2]int{start, end}, next
+}
+
+func (s *Server) album(id string) *gphoto.GoogleAlbum {
+	for _, album := range s.albums {
+		if album.ID == id {
+			return album
+		}
+	}
+	return nil
+}
+
+func (s *Server) mediaItem(id string) *gphoto.GooglePhoto {
+	for _, item := range s.items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError write error in the format of Google APIs.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    status,
+			"message": message,
+			"status":  strings.ToUpper(strings.Replace(http.StatusText(status), " ", "_", -1)),
+		},
+	})
+}
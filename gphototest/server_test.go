@@ -0,0 +1,176 @@
+package gphototest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ihippik/gphoto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, s *Server, opts ...gphoto.Option) *gphoto.Client {
+	opts = append(s.ClientOptions(), opts...)
+	opts = append(opts, gphoto.WithRepository(gphoto.NewMemoryRepository(0, 0, 0)))
+	client, err := gphoto.NewGoogleClient(ClientID, ClientSecret, RefreshToken, opts...)
+	require.NoError(t, err)
+	return client
+}
+
+func TestServer_Albums(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	album := s.AddAlbum(&gphoto.GoogleAlbum{Title: "Holidays"})
+	s.AddAlbum(&gphoto.GoogleAlbum{Title: "Work"})
+	s.AddAlbum(&gphoto.GoogleAlbum{Title: "Family"})
+	for i := 0; i < 5; i++ {
+		s.AddMediaItem(album.ID, &gphoto.GooglePhoto{Filename: fmt.Sprintf("%d.jpg", i), MimeType: "image/jpeg"}, []byte("jpeg"))
+	}
+	s.PageSize = 2
+
+	client := newTestClient(t, s)
+	defer client.Close()
+
+	albums, err := client.GetAlbumList()
+	require.NoError(t, err)
+	require.Len(t, albums, 3, "albums of every page")
+	assert.Equal(t, "Holidays", albums[0].Title)
+	assert.Equal(t, "5", albums[0].MediaItemsCount)
+
+	photos, err := client.GetPhotoByAlbum(album.ID)
+	require.NoError(t, err)
+	require.Len(t, photos, 5, "album photos of every page")
+	assert.Equal(t, "4.jpg", photos[4].Filename)
+
+	updated, err := client.UpdateAlbumTitle(album.ID, "Summer")
+	require.NoError(t, err)
+	assert.Equal(t, "Summer", updated.Title)
+	assert.Equal(t, "Summer", s.Album(album.ID).Title)
+}
+
+func TestServer_Search(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	day := func(d int) time.Time { return time.Date(2020, 5, d, 12, 0, 0, 0, time.UTC) }
+	for i := 1; i <= 5; i++ {
+		photo := &gphoto.GooglePhoto{Filename: fmt.Sprintf("%d.jpg", i), MimeType: "image/jpeg"}
+		photo.MediaMetadata.CreationTime = day(i)
+		s.AddMediaItem("", photo, nil)
+	}
+	video := &gphoto.GooglePhoto{Filename: "clip.mp4", MimeType: "video/mp4"}
+	video.MediaMetadata.CreationTime = day(3)
+	s.AddMediaItem("", video, nil)
+	s.PageSize = 2
+
+	client := newTestClient(t, s)
+	defer client.Close()
+
+	tests := []struct {
+		name   string
+		filter gphoto.SearchFilter
+		want   []string
+	}{
+		{name: "all pages", want: []string{"1.jpg", "2.jpg", "3.jpg", "4.jpg", "5.jpg", "clip.mp4"}},
+		{name: "date range", filter: gphoto.SearchFilter{From: day(2), To: day(3)}, want: []string{"2.jpg", "3.jpg", "clip.mp4"}},
+		{name: "videos", filter: gphoto.SearchFilter{MediaType: gphoto.MediaTypeVideo}, want: []string{"clip.mp4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := client.Search(tt.filter)
+			require.NoError(t, err)
+			var names []string
+			for _, item := range items {
+				names = append(names, item.Filename)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestServer_Upload(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	album := s.AddAlbum(&gphoto.GoogleAlbum{Title: "Uploads"})
+	client := newTestClient(t, s)
+	defer client.Close()
+
+	content := []byte("\x89PNG\r\n\x1a\nimage")
+	photo, err := client.Upload(album.ID, "image.png", "uploaded", bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, "image.png", photo.Filename)
+	assert.Equal(t, "uploaded", photo.Description)
+	assert.Equal(t, "image/png", photo.MimeType)
+
+	res, err := http.Get(photo.BaseURL + "=d")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int64(len(content)), res.ContentLength)
+
+	updated, err := client.UpdateMediaItemDescription(photo.ID, "changed")
+	require.NoError(t, err)
+	assert.Equal(t, "changed", updated.Description)
+	assert.Equal(t, "changed", s.MediaItem(photo.ID).Description)
+}
+
+func TestServer_Failures(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(s *Server)
+		retries int
+		wantErr bool
+	}{
+		{name: "expired token is refreshed", prepare: func(s *Server) { s.ExpireTokens() }},
+		{name: "injected 401 is refreshed", prepare: func(s *Server) { s.Fail("/v1/albums", http.StatusUnauthorized, 1) }},
+		{name: "rate limited", prepare: func(s *Server) { s.Fail("/v1/albums", http.StatusTooManyRequests, 2) }, retries: 3},
+		{name: "server error", prepare: func(s *Server) { s.Fail("/v1/albums", http.StatusInternalServerError, 1) }, retries: 2},
+		{name: "retries exhausted", prepare: func(s *Server) { s.Fail("/v1/albums", http.StatusInternalServerError, 2) }, retries: 2, wantErr: true},
+		{name: "refresh failure", prepare: func(s *Server) {
+			s.ExpireTokens()
+			s.Fail("/token", http.StatusBadRequest, 1)
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			defer s.Close()
+			s.AddAlbum(&gphoto.GoogleAlbum{Title: "Album"})
+
+			policy := gphoto.RetryPolicy{MaxAttempts: tt.retries, MinBackoff: gphoto.Duration(time.Millisecond)}
+			client := newTestClient(t, s, gphoto.WithRetryPolicy(policy))
+			defer client.Close()
+
+			_, err := client.GetAlbumList()
+			require.NoError(t, err, "first request")
+
+			tt.prepare(s)
+			albums, err := client.GetAlbumList()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, albums, 1)
+		})
+	}
+}
+
+func TestExchangeAuthCode(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	token, err := gphoto.ExchangeAuthCode(ClientID, ClientSecret, AuthCode, "http://localhost/callback", s.ClientOptions()...)
+	require.NoError(t, err)
+	assert.Equal(t, RefreshToken, token.RefreshToken)
+	assert.NotEmpty(t, token.AccessToken)
+
+	_, err = gphoto.ExchangeAuthCode(ClientID, ClientSecret, "wrong", "http://localhost/callback", s.ClientOptions()...)
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package gphoto
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var openImageErr = errors.New("open image error")
+
+// ImageSize is a variant of the photo rendered by Google, see Client.OpenImage.
+type ImageSize struct {
+	// Width and Height bound the image keeping its aspect ratio, zero leaves the dimension unbounded.
+	// Zero size is the original image.
+	Width  int
+	Height int
+	// Crop fill exactly Width x Height cutting the image.
+	Crop bool
+}
+
+// param return base url parameters of the size.
+func (s ImageSize) param() string {
+	var params []string
+	if s.Width > 0 {
+		params = append(params, "w"+strconv.Itoa(s.Width))
+	}
+	if s.Height > 0 {
+		params = append(params, "h"+strconv.Itoa(s.Height))
+	}
+	if len(params) == 0 {
+		return "=d"
+	}
+	if s.Crop {
+		params = append(params, "c")
+	}
+	return "=" + strings.Join(params, "-")
+}
+
+// OpenImage resolve a fresh base url of the photo and open its variant of the size,
+// the caller must close the returned reader.
+func (c *Client) OpenImage(mediaID string, size ImageSize) (image io.ReadCloser, err error) {
+	c, _, end := c.startSpan("gphoto.OpenImage", Attr(AttrMediaID, mediaID))
+	defer func() { end(err) }()
+
+	photo, err := c.GetPhoto(mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := c.api.download(photo.BaseURL+size.param(), 0)
+	if err != nil {
+		logrus.WithError(err).WithField("media", mediaID).Errorln(openImageErr)
+		return nil, openImageErr
+	}
+	return &downloadReader{ReadCloser: body, c: c}, nil
+}
+
+// downloadReader report bytes read from the download to the metrics on Close.
+type downloadReader struct {
+	io.ReadCloser
+	c *Client
+	n int64
+}
+
+func (r *downloadReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *downloadReader) Close() error {
+	r.c.observeDownload(r.n)
+	r.n = 0
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,55 @@
+package gphoto
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageSize_param(t *testing.T) {
+	tests := []struct {
+		name string
+		size ImageSize
+		want string
+	}{
+		{name: "original", want: "=d"},
+		{name: "width", size: ImageSize{Width: 800}, want: "=w800"},
+		{name: "box", size: ImageSize{Width: 800, Height: 600}, want: "=w800-h600"},
+		{name: "crop", size: ImageSize{Width: 200, Height: 200, Crop: true}, want: "=w200-h200-c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.size.param())
+		})
+	}
+}
+
+func TestClient_OpenImage(t *testing.T) {
+	api := new(MockedApi)
+	c, dir := newBoltClient(t, api)
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	api.On("getMediaItem", "ACCESS_TOKEN", "photo").Return(&GooglePhoto{ID: "photo", BaseURL: "http://photo/fresh"}, nil).Twice()
+	api.On("download", "http://photo/fresh=w100-h50-c", int64(0)).Return(ioutil.NopCloser(strings.NewReader("jpeg")), false, nil).Once()
+	api.On("download", "http://photo/fresh=d", int64(0)).Return(nil, false, someErr).Once()
+
+	image, err := c.OpenImage("photo", ImageSize{Width: 100, Height: 50, Crop: true})
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(image)
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", string(content))
+	assert.NoError(t, image.Close())
+
+	_, err = c.OpenImage("photo", ImageSize{})
+	assert.Equal(t, openImageErr, err)
+
+	api.On("getMediaItem", "ACCESS_TOKEN", "missing").Return(nil, notFoundErr).Once()
+	_, err = c.OpenImage("missing", ImageSize{})
+	assert.Equal(t, ErrPhotoNotExists, err)
+	api.AssertExpectations(t)
+}
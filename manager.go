@@ -0,0 +1,161 @@
+package gphoto
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+// ErrAccountNotExists is returned for accounts not added to the Manager
+// and by the cache of a Client whose account was removed.
+var ErrAccountNotExists = errors.New("account not exists")
+
+var (
+	accountErr       = errors.New("account key is empty")
+	removeAccountErr = errors.New("remove account error")
+)
+
+// Manager serve many accounts of one OAuth client. Accounts share the bolt database,
+// each one caches into its own buckets, the HTTP client and the rate limiter.
+type Manager struct {
+	clientID     string
+	clientSecret string
+	opts         *options
+	repo         *BoltRepository
+	api          *googleApi
+
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+// NewManager open the bolt database shared by accounts added later.
+// WithRepository is ignored, every other option apply to all accounts.
+func NewManager(clientID, clientSecret string, opts ...Option) (*Manager, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	db, err := initDB(o.dbPath)
+	if err != nil {
+		logrus.WithError(err).Errorln(initDbErr)
+		return nil, initDbErr
+	}
+	repo, err := newBoltRepository(db, o.keys, "")
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	api := newGoogleApi(o)
+	api.limiter = newRateLimiter(o.rateLimits, repo)
+	return &Manager{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		opts:         o,
+		repo:         repo,
+		api:          api,
+		clients:      make(map[string]*Client),
+	}, nil
+}
+
+// Add register account with its refresh token and return the account Client,
+// the token of an added account is replaced.
+func (m *Manager) Add(account, refreshToken string) (*Client, error) {
+	if account == "" {
+		return nil, accountErr
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.clients[account]; ok {
+		c.mu.Lock()
+		if c.refreshToken != refreshToken {
+			c.refreshToken, c.accessToken = refreshToken, ""
+		}
+		c.mu.Unlock()
+		return c, nil
+	}
+
+	repo, err := newBoltRepository(m.repo.DB, m.opts.keys, account)
+	if err != nil {
+		return nil, err
+	}
+	api := *m.api
+	c := newClient(m.clientID, m.clientSecret, refreshToken, &api, repo, m.opts)
+	c.limiter = m.api.limiter
+	c.startEviction()
+
+	m.clients[account] = c
+	logrus.WithField("account", account).Debugln("account added")
+	return c, nil
+}
+
+// Client return Client of the added account.
+// Closing it stops its cache eviction only, the database is closed by Manager.Close.
+func (m *Manager) Client(account string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.clients[account]
+	if !ok {
+		return nil, ErrAccountNotExists
+	}
+	return c, nil
+}
+
+// Accounts return sorted keys of added accounts.
+func (m *Manager) Accounts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	accounts := make([]string, 0, len(m.clients))
+	for account := range m.clients {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+// Remove forget the account and delete its cached data.
+// Cache calls of the account Clients fail with ErrAccountNotExists afterwards.
+func (m *Manager) Remove(account string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.clients[account]
+	if !ok {
+		return ErrAccountNotExists
+	}
+	_ = c.Close()
+	delete(m.clients, account)
+
+	err := m.repo.DB.Update(func(tx *bbolt.Tx) error {
+		if accounts := tx.Bucket([]byte(accountBucket)); accounts != nil && accounts.Bucket([]byte(account)) != nil {
+			return accounts.DeleteBucket([]byte(account))
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("account", account).Errorln(removeAccountErr)
+		return removeAccountErr
+	}
+	logrus.WithField("account", account).Debugln("account removed")
+	return nil
+}
+
+// Close stop cache eviction of all accounts and close the shared database.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.clients {
+		_ = c.Close()
+	}
+	m.clients = make(map[string]*Client)
+	return m.repo.Close()
+}
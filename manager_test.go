@@ -0,0 +1,110 @@
+package gphoto
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestManager(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			_, _ = w.Write([]byte(`{"access_token":"` + r.FormValue("refresh_token") + `"}`))
+		case "/v1/mediaItems:search":
+			owner := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer"))
+			if owner == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"mediaItems":[{"id":"` + owner + `"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "gphoto")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := []Option{
+		WithDBPath(filepath.Join(dir, googlePhotoDB)),
+		WithEndpoints(server.URL, server.URL+"/token"),
+		WithRateLimits(RateLimits{DailyRequests: 100}),
+	}
+	m, err := NewManager("id", "secret", opts...)
+	require.NoError(t, err)
+
+	alice, err := m.Add("alice", "alice-token")
+	require.NoError(t, err)
+	bob, err := m.Add("bob", "bob-token")
+	require.NoError(t, err)
+	_, err = m.Add("", "token")
+	assert.Equal(t, accountErr, err)
+
+	for owner, c := range map[string]*Client{"alice-token": alice, "bob-token": bob} {
+		photos, err := c.GetPhotoByAlbum("album")
+		require.NoError(t, err)
+		require.Len(t, photos, 1)
+		assert.Equal(t, owner, photos[0].ID)
+	}
+
+	cached, err := alice.repo.ListPhotos("album")
+	require.NoError(t, err)
+	assert.Equal(t, "alice-token", cached[0].ID, "accounts cache into their own buckets")
+	_, err = m.repo.ListPhotos("album")
+	assert.Equal(t, ErrAlbumNotExists, err)
+
+	assert.Equal(t, alice.api.(*googleApi).client, bob.api.(*googleApi).client)
+	assert.Equal(t, alice.limiter, bob.limiter)
+	usage, err := bob.Quota()
+	require.NoError(t, err)
+	assert.Equal(t, 4, usage.Reads, "accounts share the daily quota")
+
+	got, err := m.Client("alice")
+	require.NoError(t, err)
+	assert.Equal(t, alice, got)
+	_, err = m.Client("carol")
+	assert.Equal(t, ErrAccountNotExists, err)
+	assert.Equal(t, []string{"alice", "bob"}, m.Accounts())
+
+	assert.NoError(t, alice.Close(), "handle close keep the database open")
+	_, err = bob.repo.ListPhotos("album")
+	assert.NoError(t, err)
+	assert.Equal(t, cacheNotSupportedErr, bob.CompactCache())
+
+	require.NoError(t, m.Remove("bob"))
+	assert.Equal(t, ErrAccountNotExists, m.Remove("bob"))
+	_, err = bob.repo.ListPhotos("album")
+	assert.Equal(t, ErrAccountNotExists, err, "removed account cache is not used")
+	assert.Equal(t, ErrAccountNotExists, bob.repo.SavePhotos("album", nil))
+	_, err = bob.repo.ListAlbums()
+	assert.Equal(t, ErrAccountNotExists, err)
+	_, err = bob.CacheStats()
+	assert.Error(t, err)
+	_, err = bob.GetPhotoByAlbum("album")
+	assert.Error(t, err, "client of the removed account does not panic")
+	assert.Equal(t, []string{"alice"}, m.Accounts())
+	err = m.repo.DB.View(func(tx *bbolt.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte(accountBucket)).Bucket([]byte("bob")))
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+
+	m, err = NewManager("id", "secret", opts...)
+	require.NoError(t, err)
+	defer m.Close()
+	alice, err = m.Add("alice", "alice-token")
+	require.NoError(t, err)
+	cached, err = alice.repo.ListPhotos("album")
+	require.NoError(t, err)
+	assert.Len(t, cached, 1, "account cache survive restart")
+}
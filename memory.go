@@ -0,0 +1,263 @@
+package gphoto
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MemoryRepository is an in-memory repository implementation.
+// Least recently used albums are evicted when limits are exceeded,
+// records older than ttl are treated as missing.
+type MemoryRepository struct {
+	maxAlbums int
+	maxItems  int
+	ttl       time.Duration
+
+	mu     sync.Mutex
+	items  int
+	photos map[string]*list.Element
+	albums map[string]*list.Element
+	// photoLRU and albumLRU keep the most recently used entries at the front.
+	photoLRU *list.List
+	albumLRU *list.List
+}
+
+type memoryPhotos struct {
+	album   string
+	photos  []*GooglePhoto
+	savedAt time.Time
+}
+
+type memoryAlbum struct {
+	album   *GoogleAlbum
+	savedAt time.Time
+}
+
+// NewMemoryRepository make MemoryRepository instance.
+// maxAlbums limits cached album records, maxItems limits cached photos of all albums,
+// ttl limits age of the records. Zero disables the corresponding limit.
+func NewMemoryRepository(maxAlbums, maxItems int, ttl time.Duration) *MemoryRepository {
+	return &MemoryRepository{
+		maxAlbums: maxAlbums,
+		maxItems:  maxItems,
+		ttl:       ttl,
+		photos:    make(map[string]*list.Element),
+		albums:    make(map[string]*list.Element),
+		photoLRU:  list.New(),
+		albumLRU:  list.New(),
+	}
+}
+
+// Close release cached data.
+func (r *MemoryRepository) Close() error {
+	return r.clear()
+}
+
+// SavePhotos append photos to the album.
+func (r *MemoryRepository) SavePhotos(album string, photos []*GooglePhoto) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.photoEntry(album)
+	if !ok {
+		el = r.photoLRU.PushFront(&memoryPhotos{album: album})
+		r.photos[album] = el
+	}
+	entry := el.Value.(*memoryPhotos)
+	for _, photo := range photos {
+		entry.photos = append(entry.photos, clonePhoto(photo))
+	}
+	entry.savedAt = time.Now()
+	r.items += len(photos)
+	r.photoLRU.MoveToFront(el)
+
+	for r.maxItems > 0 && r.items > r.maxItems && r.photoLRU.Back() != el {
+		r.removePhotos(r.photoLRU.Back())
+	}
+	logrus.WithFields(logrus.Fields{"album": album, "count": len(photos)}).Debugln("save album photo")
+	return nil
+}
+
+// ListPhotos fetch photos of the album.
+func (r *MemoryRepository) ListPhotos(album string) ([]*GooglePhoto, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.photoEntry(album)
+	if !ok {
+		logrus.WithField("album", album).Debugln(ErrAlbumNotExists)
+		return nil, ErrAlbumNotExists
+	}
+	r.photoLRU.MoveToFront(el)
+
+	cached := el.Value.(*memoryPhotos).photos
+	photos := make([]*GooglePhoto, 0, len(cached))
+	for _, photo := range cached {
+		photos = append(photos, clonePhoto(photo))
+	}
+	return photos, nil
+}
+
+// TruncateAlbum drop photos of the album.
+func (r *MemoryRepository) TruncateAlbum(album string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if el, ok := r.photos[album]; ok {
+		r.removePhotos(el)
+	}
+	return nil
+}
+
+// UpdatePhoto replace cached records of the photo in every album keeping their order.
+func (r *MemoryRepository) UpdatePhoto(photo *GooglePhoto) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, el := range r.photos {
+		entry := el.Value.(*memoryPhotos)
+		for i, cached := range entry.photos {
+			if cached.ID == photo.ID {
+				entry.photos[i] = clonePhoto(photo)
+			}
+		}
+	}
+	return nil
+}
+
+// SaveAlbums save albums replacing previous versions.
+func (r *MemoryRepository) SaveAlbums(albums []*GoogleAlbum) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, album := range albums {
+		entry := &memoryAlbum{album: cloneAlbum(album), savedAt: now}
+		if el, ok := r.albums[album.ID]; ok {
+			el.Value = entry
+			r.albumLRU.MoveToFront(el)
+			continue
+		}
+		r.albums[album.ID] = r.albumLRU.PushFront(entry)
+	}
+
+	for r.maxAlbums > 0 && r.albumLRU.Len() > r.maxAlbums {
+		r.removeAlbum(r.albumLRU.Back())
+	}
+	logrus.WithField("count", len(albums)).Debugln("save albums")
+	return nil
+}
+
+// ListAlbums fetch all albums, the most recently used first.
+func (r *MemoryRepository) ListAlbums() ([]*GoogleAlbum, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var albums []*GoogleAlbum
+	for el := r.albumLRU.Front(); el != nil; {
+		next := el.Next()
+		entry := el.Value.(*memoryAlbum)
+		if r.expired(entry.savedAt) {
+			r.removeAlbum(el)
+		} else {
+			albums = append(albums, cloneAlbum(entry.album))
+		}
+		el = next
+	}
+	return albums, nil
+}
+
+// GetAlbum fetch album by id.
+func (r *MemoryRepository) GetAlbum(albumID string) (*GoogleAlbum, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.albums[albumID]
+	if !ok {
+		return nil, ErrAlbumNotExists
+	}
+	entry := el.Value.(*memoryAlbum)
+	if r.expired(entry.savedAt) {
+		r.removeAlbum(el)
+		return nil, ErrAlbumNotExists
+	}
+	r.albumLRU.MoveToFront(el)
+	return cloneAlbum(entry.album), nil
+}
+
+// stats count cached albums and photos.
+func (r *MemoryRepository) stats() (*CacheStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &CacheStats{Albums: r.photoLRU.Len(), Photos: r.items}, nil
+}
+
+// clear drop all cached albums and photos.
+func (r *MemoryRepository) clear() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items = 0
+	r.photos = make(map[string]*list.Element)
+	r.albums = make(map[string]*list.Element)
+	r.photoLRU.Init()
+	r.albumLRU.Init()
+	return nil
+}
+
+// photoEntry find not expired photos of the album, expired ones are dropped.
+func (r *MemoryRepository) photoEntry(album string) (*list.Element, bool) {
+	el, ok := r.photos[album]
+	if !ok {
+		return nil, false
+	}
+	if r.expired(el.Value.(*memoryPhotos).savedAt) {
+		r.removePhotos(el)
+		return nil, false
+	}
+	return el, true
+}
+
+func (r *MemoryRepository) removePhotos(el *list.Element) {
+	entry := r.photoLRU.Remove(el).(*memoryPhotos)
+	delete(r.photos, entry.album)
+	r.items -= len(entry.photos)
+	logrus.WithField("album", entry.album).Debugln("evict album photo")
+}
+
+func (r *MemoryRepository) removeAlbum(el *list.Element) {
+	entry := r.albumLRU.Remove(el).(*memoryAlbum)
+	delete(r.albums, entry.album.ID)
+}
+
+func (r *MemoryRepository) expired(savedAt time.Time) bool {
+	return r.ttl > 0 && time.Since(savedAt) > r.ttl
+}
+
+// clonePhoto copy photo so cached records are not changed by callers.
+func clonePhoto(photo *GooglePhoto) *GooglePhoto {
+	p := *photo
+	if photo.ContributorInfo != nil {
+		info := *photo.ContributorInfo
+		p.ContributorInfo = &info
+	}
+	if photo.MediaMetadata.Video != nil {
+		video := *photo.MediaMetadata.Video
+		p.MediaMetadata.Video = &video
+	}
+	return &p
+}
+
+// cloneAlbum copy album so cached records are not changed by callers.
+func cloneAlbum(album *GoogleAlbum) *GoogleAlbum {
+	a := *album
+	if album.ShareInfo != nil {
+		info := *album.ShareInfo
+		a.ShareInfo = &info
+	}
+	return &a
+}
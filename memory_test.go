@@ -0,0 +1,87 @@
+package gphoto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRepository_photos(t *testing.T) {
+	repo := NewMemoryRepository(0, 3, 0)
+
+	assert.NoError(t, repo.SavePhotos("first", []*GooglePhoto{{ID: "1"}, {ID: "2"}}))
+	assert.NoError(t, repo.SavePhotos("second", []*GooglePhoto{{ID: "3"}}))
+
+	photos, err := repo.ListPhotos("first")
+	assert.NoError(t, err)
+	assert.Equal(t, []*GooglePhoto{{ID: "1"}, {ID: "2"}}, photos)
+
+	photos[0].Description = "changed"
+	assert.NoError(t, repo.UpdatePhoto(&GooglePhoto{ID: "2", Description: "updated"}))
+	photos, _ = repo.ListPhotos("first")
+	assert.Equal(t, []*GooglePhoto{{ID: "1"}, {ID: "2", Description: "updated"}}, photos)
+
+	t.Run("evict least recently used", func(t *testing.T) {
+		assert.NoError(t, repo.SavePhotos("third", []*GooglePhoto{{ID: "4"}}))
+
+		_, err := repo.ListPhotos("second")
+		assert.Equal(t, ErrAlbumNotExists, err)
+		_, err = repo.ListPhotos("first")
+		assert.NoError(t, err)
+
+		stats, err := repo.stats()
+		assert.NoError(t, err)
+		assert.Equal(t, &CacheStats{Albums: 2, Photos: 3}, stats)
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		assert.NoError(t, repo.TruncateAlbum("first"))
+		_, err := repo.ListPhotos("first")
+		assert.Equal(t, ErrAlbumNotExists, err)
+	})
+}
+
+func TestMemoryRepository_albums(t *testing.T) {
+	repo := NewMemoryRepository(2, 0, 0)
+
+	assert.NoError(t, repo.SaveAlbums([]*GoogleAlbum{{ID: "first"}, {ID: "second"}}))
+	_, err := repo.GetAlbum("first")
+	assert.NoError(t, err)
+	assert.NoError(t, repo.SaveAlbums([]*GoogleAlbum{{ID: "third", ShareInfo: &ShareInfo{ShareToken: "token"}}}))
+
+	_, err = repo.GetAlbum("second")
+	assert.Equal(t, ErrAlbumNotExists, err)
+
+	albums, err := repo.ListAlbums()
+	assert.NoError(t, err)
+	assert.Equal(t, []*GoogleAlbum{{ID: "third", ShareInfo: &ShareInfo{ShareToken: "token"}}, {ID: "first"}}, albums)
+
+	albums[0].ShareInfo.IsJoined = true
+	album, _ := repo.GetAlbum("third")
+	assert.False(t, album.ShareInfo.IsJoined)
+}
+
+func TestMemoryRepository_ttl(t *testing.T) {
+	repo := NewMemoryRepository(0, 0, time.Millisecond)
+
+	assert.NoError(t, repo.SavePhotos("album", []*GooglePhoto{{ID: "1"}}))
+	assert.NoError(t, repo.SaveAlbums([]*GoogleAlbum{{ID: "album"}}))
+	time.Sleep(5 * time.Millisecond)
+
+	_, err := repo.ListPhotos("album")
+	assert.Equal(t, ErrAlbumNotExists, err)
+	_, err = repo.GetAlbum("album")
+	assert.Equal(t, ErrAlbumNotExists, err)
+	albums, err := repo.ListAlbums()
+	assert.NoError(t, err)
+	assert.Empty(t, albums)
+}
+
+func TestNewGoogleClient_withRepository(t *testing.T) {
+	repo := NewMemoryRepository(0, 0, 0)
+	c, err := NewGoogleClient("id", "secret", "token", WithRepository(repo))
+	assert.NoError(t, err)
+	assert.Equal(t, repo, c.repo)
+	assert.NoError(t, c.Close())
+}
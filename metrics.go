@@ -0,0 +1,95 @@
+package gphoto
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Results of cache lookups reported to Metrics.
+const (
+	// CacheHit is a lookup served from the cache.
+	CacheHit = "hit"
+	// CacheMiss is a lookup with nothing cached.
+	CacheMiss = "miss"
+	// CacheStale is a lookup whose cached records had expired urls and were fetched again.
+	CacheStale = "stale"
+)
+
+// Caches reported to Metrics.
+const (
+	CachePhotos = "photos"
+	CacheAlbums = "albums"
+)
+
+// Metrics receive measurements of the Client, implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveAPICall record a single Google API request attempt,
+	// status is zero if no response was received.
+	ObserveAPICall(endpoint string, status int, duration time.Duration)
+	// ObserveCacheLookup record a lookup of CachePhotos or CacheAlbums with
+	// CacheHit, CacheMiss or CacheStale result.
+	ObserveCacheLookup(cache, result string)
+	// ObserveTokenRefresh record an access token refresh.
+	ObserveTokenRefresh(success bool)
+	// ObserveDownload record bytes of media content downloaded.
+	ObserveDownload(bytes int64)
+}
+
+// observeAPICall report request attempt to the metrics.
+func (g *googleApi) observeAPICall(req *http.Request, res *http.Response, start time.Time) {
+	if g.metrics == nil {
+		return
+	}
+	var status int
+	if res != nil {
+		status = res.StatusCode
+	}
+	g.metrics.ObserveAPICall(g.endpoint(req), status, time.Since(start))
+}
+
+// endpoint name request for metrics, ids are replaced so the number of names is bounded,
+// e.g. "albums/{id}:share". Requests out of the Library API are "token" and "content".
+func (g *googleApi) endpoint(req *http.Request) string {
+	u := *req.URL
+	u.RawQuery = ""
+	if u.String() == g.getTokenURL {
+		return "token"
+	}
+	prefix := strings.TrimSuffix(g.getAlbumsURL, "albums")
+	if !strings.HasPrefix(u.String(), prefix) {
+		return "content"
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(u.String(), prefix), "/", 2)
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	name := parts[0] + "/{id}"
+	if i := strings.LastIndex(parts[1], ":"); i >= 0 {
+		name += parts[1][i:]
+	}
+	return name
+}
+
+// observeCacheLookup report cache lookup to the metrics and the span.
+func (c *Client) observeCacheLookup(span Span, cache, result string) {
+	span.SetAttributes(Attr(AttrCacheResult, result))
+	if c.metrics != nil {
+		c.metrics.ObserveCacheLookup(cache, result)
+	}
+}
+
+// observeTokenRefresh report access token refresh to the metrics.
+func (c *Client) observeTokenRefresh(success bool) {
+	if c.metrics != nil {
+		c.metrics.ObserveTokenRefresh(success)
+	}
+}
+
+// observeDownload report downloaded bytes to the metrics.
+func (c *Client) observeDownload(bytes int64) {
+	if c.metrics != nil && bytes > 0 {
+		c.metrics.ObserveDownload(bytes)
+	}
+}
@@ -0,0 +1,135 @@
+package gphoto
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_googleApi_endpoint(t *testing.T) {
+	api := newGoogleApi(defaultOptions())
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "token", url: defaultTokenURL, want: "token"},
+		{name: "albums", url: defaultLibraryURL + "/v1/albums", want: "albums"},
+		{name: "album", url: defaultLibraryURL + "/v1/albums/abc?updateMask=title", want: "albums/{id}"},
+		{name: "album action", url: defaultLibraryURL + "/v1/albums/abc:share", want: "albums/{id}:share"},
+		{name: "search", url: defaultLibraryURL + "/v1/mediaItems:search", want: "mediaItems:search"},
+		{name: "list", url: defaultLibraryURL + "/v1/mediaItems?pageSize=100", want: "mediaItems"},
+		{name: "content", url: "https://lh3.googleusercontent.com/lr/abc=d", want: "content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.url, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, api.endpoint(req))
+		})
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	m := NewPrometheusMetrics(0.1, 1)
+	m.ObserveAPICall("albums", http.StatusOK, 50*time.Millisecond)
+	m.ObserveAPICall("albums", http.StatusOK, 500*time.Millisecond)
+	m.ObserveAPICall("mediaItems:search", 0, 2*time.Second)
+	m.ObserveCacheLookup(CachePhotos, CacheHit)
+	m.ObserveCacheLookup(CachePhotos, CacheHit)
+	m.ObserveCacheLookup(CachePhotos, CacheStale)
+	m.ObserveTokenRefresh(true)
+	m.ObserveDownload(1024)
+
+	var buf bytes.Buffer
+	require.NoError(t, m.Write(&buf))
+	for _, line := range []string{
+		"# TYPE gphoto_api_request_duration_seconds histogram",
+		`gphoto_api_request_duration_seconds_bucket{endpoint="albums",status="200",le="0.1"} 1`,
+		`gphoto_api_request_duration_seconds_bucket{endpoint="albums",status="200",le="1"} 2`,
+		`gphoto_api_request_duration_seconds_bucket{endpoint="albums",status="200",le="+Inf"} 2`,
+		`gphoto_api_request_duration_seconds_sum{endpoint="albums",status="200"} 0.55`,
+		`gphoto_api_request_duration_seconds_bucket{endpoint="mediaItems:search",status="0",le="1"} 0`,
+		`gphoto_api_request_duration_seconds_count{endpoint="mediaItems:search",status="0"} 1`,
+		`gphoto_cache_lookups_total{cache="photos",result="hit"} 2`,
+		`gphoto_cache_lookups_total{cache="photos",result="stale"} 1`,
+		`gphoto_token_refreshes_total{result="success"} 1`,
+		`gphoto_token_refreshes_total{result="failure"} 0`,
+		"gphoto_downloaded_bytes_total 1024",
+	} {
+		assert.Contains(t, buf.String(), line+"\n")
+	}
+
+	assert.Equal(t, `endpoint="a\"b\\c\n"`, label("endpoint", "a\"b\\c\n"))
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	m := NewExpvarMetrics("gphoto_test")
+	m.ObserveAPICall("albums", http.StatusOK, time.Second)
+	m.ObserveCacheLookup(CacheAlbums, CacheMiss)
+	m.ObserveTokenRefresh(false)
+	m.ObserveDownload(10)
+
+	assert.Equal(t, "1", m.requests.Get("albums 200").String())
+	assert.Equal(t, "1", m.latency.Get("albums 200").String())
+	assert.Equal(t, "1", m.cache.Get("albums miss").String())
+	assert.Equal(t, "1", m.refresh.Get("failure").String())
+	assert.Equal(t, "10", m.Map().Get("downloaded_bytes").String())
+}
+
+func TestClient_metrics(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			_, _ = w.Write([]byte(`{"access_token":"token"}`))
+		case r.Header.Get("Authorization") != "Bearer token" && strings.HasPrefix(r.URL.Path, "/v1/"):
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v1/mediaItems:search":
+			_, _ = w.Write([]byte(`{"mediaItems":[{"id":"photo","baseUrl":"` + server.URL + `/content/photo"}]}`))
+		case r.URL.Path == "/v1/albums":
+			_, _ = w.Write([]byte(`{"albums":[{"id":"album"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	metrics := NewPrometheusMetrics()
+	client, err := NewGoogleClient("id", "secret", "refresh",
+		WithEndpoints(server.URL, server.URL+"/token"),
+		WithRepository(NewMemoryRepository(0, 0, 0)),
+		WithCacheTTL(0, time.Hour),
+		WithMetrics(metrics),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err = client.GetPhotoByAlbum("album")
+		require.NoError(t, err)
+		_, err = client.GetAlbumList()
+		require.NoError(t, err)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, metrics.Write(&buf))
+	for _, line := range []string{
+		`gphoto_api_request_duration_seconds_count{endpoint="mediaItems:search",status="401"} 1`,
+		`gphoto_api_request_duration_seconds_count{endpoint="mediaItems:search",status="200"} 1`,
+		`gphoto_api_request_duration_seconds_count{endpoint="content",status="200"} 1`,
+		`gphoto_api_request_duration_seconds_count{endpoint="albums",status="200"} 1`,
+		`gphoto_cache_lookups_total{cache="photos",result="miss"} 1`,
+		`gphoto_cache_lookups_total{cache="photos",result="hit"} 1`,
+		`gphoto_cache_lookups_total{cache="albums",result="miss"} 1`,
+		`gphoto_cache_lookups_total{cache="albums",result="hit"} 1`,
+		`gphoto_token_refreshes_total{result="success"} 1`,
+	} {
+		assert.Contains(t, buf.String(), line+"\n")
+	}
+}
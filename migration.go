@@ -0,0 +1,181 @@
+package gphoto
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+// ErrSchemaNewer is returned for databases written by a newer version of the package.
+var ErrSchemaNewer = errors.New("database schema is newer than supported")
+
+// boltMigration upgrade buckets of the database, or of a Manager account, by one schema version.
+type boltMigration func(tx bucketSet) error
+
+// boltMigrations upgrade bolt database step by step, migration N produce schema version N+1.
+// Applied migrations must never be changed, add a new one instead.
+var boltMigrations = []boltMigration{
+	// 1: photo, album and sync buckets, databases created before versioning have a subset of them.
+	func(tx bucketSet) error {
+		for _, name := range []string{photoBucket, albumBucket, syncBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 2: last access time of cached albums, existing albums are treated as used now.
+	func(tx bucketSet) error {
+		access, err := tx.CreateBucketIfNotExists([]byte(accessBucket))
+		if err != nil {
+			return err
+		}
+		now := accessTime(time.Now())
+		return tx.Bucket([]byte(photoBucket)).ForEach(func(k, v []byte) error {
+			if v != nil {
+				return nil
+			}
+			return access.Put(k, now)
+		})
+	},
+	// 3: photo keys were decimal strings sorted as text, so "10" came before "2".
+	// Keys are rewritten as big endian sequence numbers in numeric order.
+	func(tx bucketSet) error {
+		pBucket := tx.Bucket([]byte(photoBucket))
+
+		var albums [][]byte
+		err := pBucket.ForEach(func(k, v []byte) error {
+			if v == nil {
+				albums = append(albums, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, album := range albums {
+			type record struct {
+				seq   uint64
+				value []byte
+			}
+			var records []record
+			err := pBucket.Bucket(album).ForEach(func(k, v []byte) error {
+				seq, err := strconv.ParseUint(string(k), 10, 64)
+				if err != nil {
+					seq = math.MaxUint64
+				}
+				records = append(records, record{seq: seq, value: append([]byte(nil), v...)})
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			sort.SliceStable(records, func(i, j int) bool {
+				return records[i].seq < records[j].seq
+			})
+
+			if err = pBucket.DeleteBucket(album); err != nil {
+				return err
+			}
+			albumBucket, err := pBucket.CreateBucket(album)
+			if err != nil {
+				return err
+			}
+			for i, rec := range records {
+				if err = albumBucket.Put(photoKey(uint64(i+1)), rec.value); err != nil {
+					return err
+				}
+			}
+			if err = albumBucket.SetSequence(uint64(len(records))); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 4: daily request counts of the quota ledger.
+	func(tx bucketSet) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(quotaBucket))
+		return err
+	},
+}
+
+// migrateBolt apply migrations missing in the database, each one in its own transaction.
+func migrateBolt(db *bbolt.DB, migrations []boltMigration) error {
+	return migrateAccount(db, "", migrations)
+}
+
+// migrateAccount apply migrations missing in buckets of the account, its bucket is created first.
+func migrateAccount(db *bbolt.DB, account string, migrations []boltMigration) error {
+	if account != "" {
+		err := db.Update(func(tx *bbolt.Tx) error {
+			accounts, err := tx.CreateBucketIfNotExists([]byte(accountBucket))
+			if err != nil {
+				return err
+			}
+			_, err = accounts.CreateBucketIfNotExists([]byte(account))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	version, err := accountSchemaVersion(db, account)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		logrus.WithFields(logrus.Fields{"version": version, "supported": len(migrations)}).Errorln(ErrSchemaNewer)
+		return ErrSchemaNewer
+	}
+
+	for ; version < len(migrations); version++ {
+		err = db.Update(func(tx *bbolt.Tx) error {
+			buckets := accountBuckets(tx, account)
+			if err := migrations[version](buckets); err != nil {
+				return err
+			}
+			meta, err := buckets.CreateBucketIfNotExists([]byte(metaBucket))
+			if err != nil {
+				return err
+			}
+			return meta.Put([]byte(schemaKey), []byte(strconv.Itoa(version+1)))
+		})
+		if err != nil {
+			return err
+		}
+		logrus.WithFields(logrus.Fields{"version": version + 1, "account": account}).Debugln("bolt schema migrated")
+	}
+	return nil
+}
+
+// schemaVersion read schema version of the database, databases without meta bucket have version 0.
+func schemaVersion(db *bbolt.DB) (int, error) {
+	return accountSchemaVersion(db, "")
+}
+
+// accountSchemaVersion read schema version of buckets of the account.
+func accountSchemaVersion(db *bbolt.DB, account string) (int, error) {
+	var version int
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		meta := accountBuckets(tx, account).Bucket([]byte(metaBucket))
+		if meta == nil {
+			return nil
+		}
+		v := meta.Get([]byte(schemaKey))
+		if v == nil {
+			return nil
+		}
+		var err error
+		version, err = strconv.Atoi(string(v))
+		return err
+	})
+	return version, err
+}
@@ -0,0 +1,147 @@
+package gphoto
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func newTempBolt(t *testing.T) (*bbolt.DB, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "gphoto")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	db, err := initDB(filepath.Join(dir, "gphoto.db"))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return db, dir
+}
+
+func TestNewBoltRepository_migrate(t *testing.T) {
+	db, dir := newTempBolt(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	t.Run("unversioned database", func(t *testing.T) {
+		err := db.Update(func(tx *bbolt.Tx) error {
+			b, err := tx.CreateBucket([]byte(photoBucket))
+			if err != nil {
+				return err
+			}
+			album, err := b.CreateBucket([]byte("album"))
+			if err != nil {
+				return err
+			}
+			return album.Put([]byte("1"), []byte(`{"id":"photo"}`))
+		})
+		assert.NoError(t, err)
+
+		repo, err := NewBoltRepository(db)
+		assert.NoError(t, err)
+		version, err := schemaVersion(db)
+		assert.NoError(t, err)
+		assert.Equal(t, len(boltMigrations), version)
+
+		photos, err := repo.ListPhotos("album")
+		assert.NoError(t, err)
+		assert.Equal(t, []*GooglePhoto{{ID: "photo"}}, photos)
+	})
+
+	t.Run("newer database", func(t *testing.T) {
+		err := db.Update(func(tx *bbolt.Tx) error {
+			return tx.Bucket([]byte(metaBucket)).Put([]byte(schemaKey), []byte(strconv.Itoa(len(boltMigrations)+1)))
+		})
+		assert.NoError(t, err)
+
+		_, err = NewBoltRepository(db)
+		assert.Equal(t, ErrSchemaNewer, err)
+	})
+}
+
+func Test_migrateBolt(t *testing.T) {
+	db, dir := newTempBolt(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	var applied []int
+	step := func(n int) boltMigration {
+		return func(tx bucketSet) error {
+			applied = append(applied, n)
+			return nil
+		}
+	}
+
+	assert.NoError(t, migrateBolt(db, []boltMigration{step(1), step(2)}))
+	assert.NoError(t, migrateBolt(db, []boltMigration{step(1), step(2), step(3)}))
+	assert.Equal(t, []int{1, 2, 3}, applied)
+
+	t.Run("failed migration keep version", func(t *testing.T) {
+		fail := func(tx bucketSet) error { return errors.New("fail") }
+		assert.Error(t, migrateBolt(db, []boltMigration{step(1), step(2), step(3), fail}))
+
+		version, err := schemaVersion(db)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, version)
+	})
+}
+
+func Test_boltMigrations_photoKeys(t *testing.T) {
+	db, dir := newTempBolt(t)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	assert.NoError(t, migrateBolt(db, boltMigrations[:2]))
+	err := db.Update(func(tx *bbolt.Tx) error {
+		album, err := tx.Bucket([]byte(photoBucket)).CreateBucket([]byte("album"))
+		if err != nil {
+			return err
+		}
+		for _, id := range []string{"1", "2", "10"} {
+			if err := album.Put([]byte(id), []byte(`{"id":"`+id+`"}`)); err != nil {
+				return err
+			}
+		}
+		return album.SetSequence(10)
+	})
+	assert.NoError(t, err)
+
+	repo, err := NewBoltRepository(db)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.SavePhotos("album", []*GooglePhoto{{ID: "11"}}))
+
+	photos, err := repo.ListPhotos("album")
+	assert.NoError(t, err)
+	assert.Equal(t, []*GooglePhoto{{ID: "1"}, {ID: "2"}, {ID: "10"}, {ID: "11"}}, photos)
+}
+
+func TestNewGoogleClient_newerSchema(t *testing.T) {
+	db, dir := newTempBolt(t)
+	defer os.RemoveAll(dir)
+	_, err := NewBoltRepository(db)
+	assert.NoError(t, err)
+	err = db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(metaBucket)).Put([]byte(schemaKey), []byte(strconv.Itoa(len(boltMigrations)+1)))
+	})
+	assert.NoError(t, err)
+	path := db.Path()
+	assert.NoError(t, db.Close())
+
+	c, err := NewGoogleClient("id", "secret", "token", WithDBPath(path))
+	assert.Equal(t, ErrSchemaNewer, err)
+	assert.Nil(t, c)
+
+	db, err = bbolt.Open(path, 0600, &bbolt.Options{Timeout: 100 * time.Millisecond})
+	if assert.NoError(t, err, "database file is not locked") {
+		_ = db.Close()
+	}
+}
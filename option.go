@@ -0,0 +1,130 @@
+package gphoto
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configure Client created by NewGoogleClient.
+type Option func(*options)
+
+type options struct {
+	dbPath      string
+	httpTimeout time.Duration
+	retry       RetryPolicy
+	photoTTL    time.Duration
+	albumTTL    time.Duration
+	repo        Repository
+	limits      CacheLimits
+	keys        KeyProvider
+	libraryURL  string
+	tokenURL    string
+	transport   http.RoundTripper
+	metrics     Metrics
+	tracer      Tracer
+	rateLimits  RateLimits
+}
+
+func defaultOptions() *options {
+	return &options{
+		dbPath:      googlePhotoDB,
+		httpTimeout: defaultTimeout,
+		libraryURL:  defaultLibraryURL,
+		tokenURL:    defaultTokenURL,
+	}
+}
+
+// WithDBPath set path of the bolt database file.
+func WithDBPath(path string) Option {
+	return func(o *options) {
+		o.dbPath = path
+	}
+}
+
+// WithHTTPTimeout set timeout of every Google API request. Media content downloads
+// are limited only in waiting for response headers, reading the content is not bounded.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.httpTimeout = timeout
+	}
+}
+
+// WithRetryPolicy enable retries of failed Google API requests.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+// WithCacheTTL set how long cached album photos are served without checking
+// their base urls, and how long the album list is kept in memory.
+// Zero disables the corresponding cache.
+func WithCacheTTL(photoTTL, albumTTL time.Duration) Option {
+	return func(o *options) {
+		o.photoTTL = photoTTL
+		o.albumTTL = albumTTL
+	}
+}
+
+// WithRepository use repo as the Client cache instead of the bolt database,
+// WithDBPath is ignored then.
+func WithRepository(repo Repository) Option {
+	return func(o *options) {
+		o.repo = repo
+	}
+}
+
+// WithCacheLimits bound the cache size, limits are enforced every limits.EvictInterval
+// and on Client.EvictCache call.
+func WithCacheLimits(limits CacheLimits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// WithEncryption encrypt values of the bolt database with AES-GCM keys of the provider.
+func WithEncryption(keys KeyProvider) Option {
+	return func(o *options) {
+		o.keys = keys
+	}
+}
+
+// WithEndpoints send requests to libraryURL instead of https://photoslibrary.googleapis.com
+// and refresh tokens at tokenURL, e.g. to run against a fake server in tests.
+func WithEndpoints(libraryURL, tokenURL string) Option {
+	return func(o *options) {
+		o.libraryURL = strings.TrimSuffix(libraryURL, "/")
+		o.tokenURL = tokenURL
+	}
+}
+
+// WithTransport send Google API requests through rt, e.g. a Recorder.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = rt
+	}
+}
+
+// WithMetrics report API calls, cache lookups, token refreshes and downloads to m.
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// WithTracer trace Client operations, repository calls and Google API requests,
+// see Client.WithContext to make them children of the caller span.
+func WithTracer(tracer Tracer) Option {
+	return func(o *options) {
+		o.tracer = tracer
+	}
+}
+
+// WithRateLimits limit Google API requests and downloads, daily requests are counted
+// in the bolt database, or in memory for other repositories.
+func WithRateLimits(limits RateLimits) Option {
+	return func(o *options) {
+		o.rateLimits = limits
+	}
+}
@@ -1,28 +1,126 @@
 package gphoto
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Video processing statuses of VideoMetadata.
+const (
+	VideoStatusUnspecified = "UNSPECIFIED"
+	VideoStatusProcessing  = "PROCESSING"
+	VideoStatusReady       = "READY"
+	VideoStatusFailed      = "FAILED"
+)
 
 type googlePhotoResponse struct {
-	GooglePhotos []*GooglePhoto `json:"mediaItems"`
+	GooglePhotos  []*GooglePhoto `json:"mediaItems"`
+	NextPageToken string         `json:"nextPageToken"`
 }
 
-// GooglePhoto represent google album structure received from api.
+// GooglePhoto represent google media item structure received from api.
 type GooglePhoto struct {
-	ID            string `json:"id"`
-	ProductURL    string `json:"productUrl"`
-	BaseURL       string `json:"baseUrl"`
-	MimeType      string `json:"mimeType"`
-	MediaMetadata struct {
-		CreationTime time.Time `json:"creationTime"`
-		Width        string    `json:"width"`
-		Height       string    `json:"height"`
-		Photo        struct {
-			CameraMake      string  `json:"cameraMake"`
-			CameraModel     string  `json:"cameraModel"`
-			FocalLength     int     `json:"focalLength"`
-			ApertureFNumber float64 `json:"apertureFNumber"`
-			IsoEquivalent   int     `json:"isoEquivalent"`
-		} `json:"photo"`
-	} `json:"mediaMetadata"`
-	Filename string `json:"filename"`
+	ID              string           `json:"id"`
+	Description     string           `json:"description,omitempty"`
+	ProductURL      string           `json:"productUrl"`
+	BaseURL         string           `json:"baseUrl"`
+	MimeType        string           `json:"mimeType"`
+	MediaMetadata   MediaMetadata    `json:"mediaMetadata"`
+	ContributorInfo *ContributorInfo `json:"contributorInfo,omitempty"`
+	Filename        string           `json:"filename"`
+}
+
+// IsVideo report whether media item is a video.
+func (p *GooglePhoto) IsVideo() bool {
+	return p.MediaMetadata.Video != nil || strings.HasPrefix(p.MimeType, "video/")
+}
+
+// MediaMetadata represent metadata of the media item, only one of Photo and Video is filled by api.
+type MediaMetadata struct {
+	CreationTime time.Time      `json:"creationTime"`
+	Width        int64          `json:"width"`
+	Height       int64          `json:"height"`
+	Photo        PhotoMetadata  `json:"photo"`
+	Video        *VideoMetadata `json:"video,omitempty"`
+}
+
+// UnmarshalJSON decode metadata, api sends width and height as strings
+// while they are cached as numbers.
+func (m *MediaMetadata) UnmarshalJSON(data []byte) error {
+	type metadata MediaMetadata
+	aux := struct {
+		*metadata
+		Width  json.RawMessage `json:"width"`
+		Height json.RawMessage `json:"height"`
+	}{metadata: (*metadata)(m)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if m.Width, err = parseInt64(aux.Width); err != nil {
+		return err
+	}
+	m.Height, err = parseInt64(aux.Height)
+	return err
+}
+
+// PhotoMetadata represent photo specific metadata.
+type PhotoMetadata struct {
+	CameraMake      string       `json:"cameraMake"`
+	CameraModel     string       `json:"cameraModel"`
+	FocalLength     float64      `json:"focalLength"`
+	ApertureFNumber float64      `json:"apertureFNumber"`
+	IsoEquivalent   int          `json:"isoEquivalent"`
+	ExposureTime    ExposureTime `json:"exposureTime"`
+}
+
+// ExposureTime is a shutter speed, api sends it as a string of seconds like "0.008s".
+type ExposureTime time.Duration
+
+// UnmarshalJSON parse exposure time string, empty value is zero.
+func (e *ExposureTime) UnmarshalJSON(data []byte) error {
+	value := string(bytes.Trim(data, `"`))
+	if value == "" || value == "null" {
+		*e = 0
+		return nil
+	}
+	exposure, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*e = ExposureTime(exposure)
+	return nil
+}
+
+// MarshalJSON encode exposure time as a string of seconds.
+func (e ExposureTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatFloat(time.Duration(e).Seconds(), 'f', -1, 64) + "s")
+}
+
+// VideoMetadata represent video specific metadata.
+type VideoMetadata struct {
+	CameraMake  string  `json:"cameraMake"`
+	CameraModel string  `json:"cameraModel"`
+	Fps         float64 `json:"fps"`
+	Status      string  `json:"status"`
+}
+
+// ContributorInfo represent the user who added media item to a shared album.
+type ContributorInfo struct {
+	ProfilePictureBaseURL string `json:"profilePictureBaseUrl"`
+	DisplayName           string `json:"displayName"`
+}
+
+// parseInt64 parse a number encoded either as JSON number or string, empty value is zero.
+func parseInt64(raw json.RawMessage) (int64, error) {
+	value := string(bytes.Trim(raw, `"`))
+	if value == "" || value == "null" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
 }
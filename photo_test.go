@@ -0,0 +1,93 @@
+package gphoto
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGooglePhoto_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    func() *GooglePhoto
+		wantErr bool
+	}{
+		{
+			name: "api photo",
+			payload: `{"id":"photo","description":"sunset","mediaMetadata":{"creationTime":"2019-07-14T10:00:00Z",` +
+				`"width":"4032","height":"3024","photo":{"cameraMake":"Google","focalLength":4.44,` +
+				`"apertureFNumber":1.8,"isoEquivalent":60,"exposureTime":"0.008s"}},` +
+				`"contributorInfo":{"displayName":"John"}}`,
+			want: func() *GooglePhoto {
+				p := &GooglePhoto{ID: "photo", Description: "sunset", ContributorInfo: &ContributorInfo{DisplayName: "John"}}
+				p.MediaMetadata.CreationTime = time.Date(2019, 7, 14, 10, 0, 0, 0, time.UTC)
+				p.MediaMetadata.Width, p.MediaMetadata.Height = 4032, 3024
+				p.MediaMetadata.Photo = PhotoMetadata{
+					CameraMake:      "Google",
+					FocalLength:     4.44,
+					ApertureFNumber: 1.8,
+					IsoEquivalent:   60,
+					ExposureTime:    ExposureTime(8 * time.Millisecond),
+				}
+				return p
+			},
+		},
+		{
+			name:    "api video",
+			payload: `{"id":"video","mediaMetadata":{"width":"1920","height":"1080","video":{"fps":29.97,"status":"READY"}}}`,
+			want: func() *GooglePhoto {
+				p := &GooglePhoto{ID: "video"}
+				p.MediaMetadata.Width, p.MediaMetadata.Height = 1920, 1080
+				p.MediaMetadata.Video = &VideoMetadata{Fps: 29.97, Status: VideoStatusReady}
+				return p
+			},
+		},
+		{
+			name:    "legacy cached record",
+			payload: `{"id":"legacy","mediaMetadata":{"creationTime":"0001-01-01T00:00:00Z","width":"","height":"","photo":{"focalLength":4}}}`,
+			want: func() *GooglePhoto {
+				p := &GooglePhoto{ID: "legacy"}
+				p.MediaMetadata.Photo.FocalLength = 4
+				return p
+			},
+		},
+		{
+			name:    "invalid width",
+			payload: `{"id":"photo","mediaMetadata":{"width":"wide"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid exposure time",
+			payload: `{"id":"photo","mediaMetadata":{"photo":{"exposureTime":"fast"}}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got GooglePhoto
+			err := json.Unmarshal([]byte(tt.payload), &got)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want(), &got)
+
+			// cached form must load back into the same value.
+			data, err := json.Marshal(&got)
+			assert.NoError(t, err)
+			var cached GooglePhoto
+			assert.NoError(t, json.Unmarshal(data, &cached))
+			assert.Equal(t, got, cached)
+		})
+	}
+}
+
+func TestGooglePhoto_IsVideo(t *testing.T) {
+	assert.True(t, (&GooglePhoto{MimeType: "video/mp4"}).IsVideo())
+	assert.True(t, (&GooglePhoto{MediaMetadata: MediaMetadata{Video: &VideoMetadata{}}}).IsVideo())
+	assert.False(t, (&GooglePhoto{MimeType: "image/jpeg"}).IsVideo())
+}
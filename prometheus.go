@@ -0,0 +1,170 @@
+package gphoto
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are upper bounds in seconds of the API latency histogram buckets.
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics is a Metrics collecting measurements in memory and exposing them
+// in the Prometheus text format, mount it as a http.Handler on the metrics path.
+type PrometheusMetrics struct {
+	mu         sync.Mutex
+	buckets    []float64
+	api        map[apiCallKey]*histogram
+	cache      map[[2]string]uint64
+	refresh    map[bool]uint64
+	downloaded uint64
+}
+
+type apiCallKey struct {
+	endpoint string
+	status   int
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewPrometheusMetrics make PrometheusMetrics with latency histogram buckets in seconds,
+// DefaultLatencyBuckets are used if none are given.
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &PrometheusMetrics{
+		buckets: buckets,
+		api:     make(map[apiCallKey]*histogram),
+		cache:   make(map[[2]string]uint64),
+		refresh: make(map[bool]uint64),
+	}
+}
+
+// ObserveAPICall implement Metrics.
+func (m *PrometheusMetrics) ObserveAPICall(endpoint string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := apiCallKey{endpoint: endpoint, status: status}
+	h, ok := m.api[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.api[key] = h
+	}
+	seconds := duration.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// ObserveCacheLookup implement Metrics.
+func (m *PrometheusMetrics) ObserveCacheLookup(cache, result string) {
+	m.mu.Lock()
+	m.cache[[2]string{cache, result}]++
+	m.mu.Unlock()
+}
+
+// ObserveTokenRefresh implement Metrics.
+func (m *PrometheusMetrics) ObserveTokenRefresh(success bool) {
+	m.mu.Lock()
+	m.refresh[success]++
+	m.mu.Unlock()
+}
+
+// ObserveDownload implement Metrics.
+func (m *PrometheusMetrics) ObserveDownload(bytes int64) {
+	m.mu.Lock()
+	m.downloaded += uint64(bytes)
+	m.mu.Unlock()
+}
+
+// ServeHTTP write metrics in the Prometheus text format.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.Write(w)
+}
+
+// Write metrics in the Prometheus text format.
+func (m *PrometheusMetrics) Write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+
+	keys := make([]apiCallKey, 0, len(m.api))
+	for key := range m.api {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].endpoint != keys[j].endpoint {
+			return keys[i].endpoint < keys[j].endpoint
+		}
+		return keys[i].status < keys[j].status
+	})
+	writeHeader(buf, "gphoto_api_request_duration_seconds", "histogram", "Latency of Google API request attempts.")
+	for _, key := range keys {
+		h := m.api[key]
+		labels := label("endpoint", key.endpoint) + "," + label("status", strconv.Itoa(key.status))
+		for i, bound := range m.buckets {
+			fmt.Fprintf(buf, "gphoto_api_request_duration_seconds_bucket{%s,%s} %d\n",
+				labels, label("le", strconv.FormatFloat(bound, 'g', -1, 64)), h.counts[i])
+		}
+		fmt.Fprintf(buf, "gphoto_api_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(buf, "gphoto_api_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(buf, "gphoto_api_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	lookups := make([][2]string, 0, len(m.cache))
+	for key := range m.cache {
+		lookups = append(lookups, key)
+	}
+	sort.Slice(lookups, func(i, j int) bool {
+		if lookups[i][0] != lookups[j][0] {
+			return lookups[i][0] < lookups[j][0]
+		}
+		return lookups[i][1] < lookups[j][1]
+	})
+	writeHeader(buf, "gphoto_cache_lookups_total", "counter", "Cache lookups by result.")
+	for _, key := range lookups {
+		fmt.Fprintf(buf, "gphoto_cache_lookups_total{%s,%s} %d\n", label("cache", key[0]), label("result", key[1]), m.cache[key])
+	}
+
+	writeHeader(buf, "gphoto_token_refreshes_total", "counter", "Access token refreshes.")
+	fmt.Fprintf(buf, "gphoto_token_refreshes_total{result=\"success\"} %d\n", m.refresh[true])
+	fmt.Fprintf(buf, "gphoto_token_refreshes_total{result=\"failure\"} %d\n", m.refresh[false])
+
+	writeHeader(buf, "gphoto_downloaded_bytes_total", "counter", "Bytes of downloaded media content.")
+	fmt.Fprintf(buf, "gphoto_downloaded_bytes_total %d\n", m.downloaded)
+
+	return buf.Flush()
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// labelEscaper escape label values as the Prometheus text format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// label format label pair.
+func label(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `"`
+}
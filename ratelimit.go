@@ -0,0 +1,492 @@
+package gphoto
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+// Priorities of requests, see WithPriority.
+const (
+	// PriorityInteractive requests may use the whole budget, it is the default.
+	PriorityInteractive Priority = iota
+	// PriorityBatch requests leave RateLimits.InteractiveReserve of the budget to interactive ones.
+	PriorityBatch
+)
+
+const (
+	quotaBucket = "quota"
+	quotaRead   = "read"
+	quotaWrite  = "write"
+	// quotaDays is how many days of the quota ledger are kept.
+	quotaDays = 31
+	// quotaFlushInterval is how often request counts are written to the repository.
+	quotaFlushInterval = 10 * time.Second
+	dayLayout          = "2006-01-02"
+)
+
+// ErrQuotaExceeded is returned when the daily request budget is used up.
+var ErrQuotaExceeded = errors.New("daily request quota exceeded")
+
+var (
+	quotaErr    = errors.New("quota ledger error")
+	noLimitsErr = errors.New("rate limits are not set")
+)
+
+// RateLimits bound Google API usage on the client side, zero disables the corresponding limit.
+type RateLimits struct {
+	// ReadsPerMinute limit album, media item and search requests.
+	ReadsPerMinute int `json:"reads_per_minute" yaml:"reads_per_minute"`
+	// WritesPerMinute limit upload, create, update and share requests.
+	WritesPerMinute int `json:"writes_per_minute" yaml:"writes_per_minute"`
+	// DownloadBytesPerSecond limit media content downloads.
+	DownloadBytesPerSecond int64 `json:"download_bytes_per_second" yaml:"download_bytes_per_second"`
+	// DailyRequests limit Library API requests per UTC day, they are counted in the quota ledger.
+	DailyRequests int `json:"daily_requests" yaml:"daily_requests"`
+	// InteractiveReserve is a share from 0 to 1 of per-minute and daily budgets
+	// PriorityBatch requests can not use.
+	InteractiveReserve float64 `json:"interactive_reserve" yaml:"interactive_reserve"`
+}
+
+// enabled report whether any limit is set.
+func (l RateLimits) enabled() bool {
+	return l.ReadsPerMinute > 0 || l.WritesPerMinute > 0 || l.DownloadBytesPerSecond > 0 || l.DailyRequests > 0
+}
+
+// Priority of the requests made with a context.
+type Priority int
+
+type priorityKey struct{}
+
+// WithPriority return ctx making requests of Client.WithContext with the priority.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityOf(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityKey{}).(Priority)
+	return priority
+}
+
+// QuotaUsage is a daily request budget usage.
+type QuotaUsage struct {
+	// Day is the UTC day as YYYY-MM-DD.
+	Day    string
+	Reads  int
+	Writes int
+	// Limit is RateLimits.DailyRequests, Remaining and BatchRemaining are zero if it is not set.
+	Limit          int
+	Remaining      int
+	BatchRemaining int
+}
+
+// quotaLedger count requests per day.
+type quotaLedger interface {
+	// takeQuota count a request of the kind unless the day total reached limit, zero means no limit.
+	takeQuota(day, kind string, limit int) error
+	// quotaUsage return request counts of the day by kind.
+	quotaUsage(day string) (map[string]int, error)
+	// flush write counted requests to the storage.
+	flush() error
+}
+
+// quotaStore is implemented by repositories able to keep request counts per day.
+type quotaStore interface {
+	// addQuota add request counts of the day by kind, days older than quotaDays are dropped.
+	addQuota(day string, counts map[string]int) error
+	// quotaUsage return request counts of the day by kind.
+	quotaUsage(day string) (map[string]int, error)
+}
+
+// Quota return request budget usage of the current UTC day.
+func (c *Client) Quota() (*QuotaUsage, error) {
+	if c.limiter == nil {
+		return nil, noLimitsErr
+	}
+
+	usage, err := c.limiter.usage()
+	if err != nil {
+		logrus.WithError(err).Errorln(quotaErr)
+		return nil, quotaErr
+	}
+	return usage, nil
+}
+
+// rateLimiter hold token buckets and the quota ledger of a client.
+type rateLimiter struct {
+	limits RateLimits
+	reads  *tokenBucket
+	writes *tokenBucket
+	bytes  *tokenBucket
+	ledger quotaLedger
+	now    func() time.Time
+}
+
+// newRateLimiter make limiter keeping request counts in store, nil is returned if no limit is set.
+// Without store requests are counted in memory only.
+func newRateLimiter(limits RateLimits, store quotaStore) *rateLimiter {
+	if !limits.enabled() {
+		return nil
+	}
+	var ledger quotaLedger = newMemoryLedger()
+	if store != nil {
+		ledger = newStoredLedger(store)
+	}
+	if limits.InteractiveReserve < 0 {
+		limits.InteractiveReserve = 0
+	} else if limits.InteractiveReserve > 1 {
+		limits.InteractiveReserve = 1
+	}
+
+	l := &rateLimiter{limits: limits, ledger: ledger, now: time.Now}
+	if limits.ReadsPerMinute > 0 {
+		l.reads = newTokenBucket(float64(limits.ReadsPerMinute)/60, float64(limits.ReadsPerMinute))
+	}
+	if limits.WritesPerMinute > 0 {
+		l.writes = newTokenBucket(float64(limits.WritesPerMinute)/60, float64(limits.WritesPerMinute))
+	}
+	if limits.DownloadBytesPerSecond > 0 {
+		l.bytes = newTokenBucket(float64(limits.DownloadBytesPerSecond), float64(limits.DownloadBytesPerSecond))
+	}
+	return l
+}
+
+// wait take daily quota and a token of the request, blocking until the token is available.
+// Token endpoint and media content requests are not counted.
+func (l *rateLimiter) wait(req *http.Request, endpoint string) error {
+	if endpoint == "token" || endpoint == "content" {
+		return nil
+	}
+	ctx := req.Context()
+	kind, bucket := quotaRead, l.reads
+	if req.Method != http.MethodGet && !strings.HasSuffix(endpoint, ":search") && !strings.HasSuffix(endpoint, ":batchGet") {
+		kind, bucket = quotaWrite, l.writes
+	}
+
+	reserve := 0.0
+	if priorityOf(ctx) == PriorityBatch {
+		reserve = l.limits.InteractiveReserve
+	}
+	if err := l.ledger.takeQuota(l.day(), kind, l.dailyLimit(reserve)); err != nil {
+		return err
+	}
+	if bucket == nil {
+		return nil
+	}
+	return bucket.wait(ctx, 1, reserve*bucket.burst)
+}
+
+// throttle limit reading of the media content body.
+func (l *rateLimiter) throttle(ctx context.Context, body io.ReadCloser) io.ReadCloser {
+	if l.bytes == nil {
+		return body
+	}
+	return &throttledReader{ReadCloser: body, ctx: ctx, bucket: l.bytes}
+}
+
+// dailyLimit return daily request limit leaving reserve share of it.
+func (l *rateLimiter) dailyLimit(reserve float64) int {
+	if l.limits.DailyRequests <= 0 {
+		return 0
+	}
+	limit := int(float64(l.limits.DailyRequests) * (1 - reserve))
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
+
+// flush write counted requests to the storage.
+func (l *rateLimiter) flush() error {
+	return l.ledger.flush()
+}
+
+func (l *rateLimiter) day() string {
+	return l.now().UTC().Format(dayLayout)
+}
+
+func (l *rateLimiter) usage() (*QuotaUsage, error) {
+	day := l.day()
+	counts, err := l.ledger.quotaUsage(day)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &QuotaUsage{Day: day, Reads: counts[quotaRead], Writes: counts[quotaWrite], Limit: l.limits.DailyRequests}
+	if usage.Limit > 0 {
+		used := usage.Reads + usage.Writes
+		usage.Remaining = nonNegative(usage.Limit - used)
+		usage.BatchRemaining = nonNegative(l.dailyLimit(l.limits.InteractiveReserve) - used)
+	}
+	return usage, nil
+}
+
+func nonNegative(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// tokenBucket is refilled at rate tokens per second up to burst.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now(), now: time.Now}
+}
+
+// wait take n tokens leaving at least reserve of them, blocking until they are available.
+func (b *tokenBucket) wait(ctx context.Context, n, reserve float64) error {
+	if reserve > b.burst-n {
+		reserve = b.burst - n
+	}
+	for {
+		b.mu.Lock()
+		now := b.now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		if b.tokens-n >= reserve {
+			b.tokens -= n
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((n + reserve - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// throttledReader take a token for every byte read.
+type throttledReader struct {
+	io.ReadCloser
+	ctx    context.Context
+	bucket *tokenBucket
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > int(r.bucket.burst) {
+		p = p[:int(r.bucket.burst)]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := r.bucket.wait(r.ctx, float64(n), 0); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// memoryLedger is a quota ledger of repositories without one, it is lost on restart.
+type memoryLedger struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int
+}
+
+func newMemoryLedger() *memoryLedger {
+	return &memoryLedger{counts: make(map[string]map[string]int)}
+}
+
+func (m *memoryLedger) takeQuota(day, kind string, limit int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts, ok := m.counts[day]
+	if !ok {
+		// a new day starts, older ones are not needed.
+		counts = make(map[string]int)
+		m.counts = map[string]map[string]int{day: counts}
+	}
+	if limit > 0 && counts[quotaRead]+counts[quotaWrite] >= limit {
+		return ErrQuotaExceeded
+	}
+	counts[kind]++
+	return nil
+}
+
+func (m *memoryLedger) quotaUsage(day string) (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := make(map[string]int)
+	for kind, count := range m.counts[day] {
+		usage[kind] = count
+	}
+	return usage, nil
+}
+
+func (m *memoryLedger) flush() error {
+	return nil
+}
+
+// storedLedger count requests of the current day in memory and write them to the store
+// every quotaFlushInterval and on flush, so requests do not wait for the storage.
+type storedLedger struct {
+	store quotaStore
+	now   func() time.Time
+
+	mu      sync.Mutex
+	day     string
+	counts  map[string]int
+	pending map[string]int
+	flushed time.Time
+}
+
+func newStoredLedger(store quotaStore) *storedLedger {
+	return &storedLedger{store: store, now: time.Now, pending: make(map[string]int)}
+}
+
+func (l *storedLedger) takeQuota(day, kind string, limit int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if day != l.day {
+		if err := l.flushLocked(); err != nil {
+			logrus.WithError(err).WithField("day", l.day).Errorln(quotaErr)
+			l.pending = make(map[string]int)
+		}
+		counts, err := l.store.quotaUsage(day)
+		if err != nil {
+			return err
+		}
+		// the first request of the day is written at once, it drops the oldest day.
+		l.day, l.counts, l.flushed = day, counts, time.Time{}
+	}
+
+	if limit > 0 && l.counts[quotaRead]+l.counts[quotaWrite] >= limit {
+		return ErrQuotaExceeded
+	}
+	l.counts[kind]++
+	l.pending[kind]++
+
+	if l.now().Sub(l.flushed) >= quotaFlushInterval {
+		if err := l.flushLocked(); err != nil {
+			// pending counts are kept for the next flush.
+			logrus.WithError(err).WithField("day", l.day).Errorln(quotaErr)
+		}
+	}
+	return nil
+}
+
+func (l *storedLedger) quotaUsage(day string) (map[string]int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if day != l.day {
+		return l.store.quotaUsage(day)
+	}
+	usage := make(map[string]int)
+	for kind, count := range l.counts {
+		usage[kind] = count
+	}
+	return usage, nil
+}
+
+func (l *storedLedger) flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.flushLocked()
+}
+
+func (l *storedLedger) flushLocked() error {
+	l.flushed = l.now()
+	if len(l.pending) == 0 {
+		return nil
+	}
+	if err := l.store.addQuota(l.day, l.pending); err != nil {
+		return err
+	}
+	l.pending = make(map[string]int)
+	return nil
+}
+
+// addQuota add request counts to the quota bucket, days older than quotaDays are dropped.
+func (r *BoltRepository) addQuota(day string, counts map[string]int) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.update(func(tx *bbolt.Tx) error {
+		bucket, err := r.buckets(tx).CreateBucketIfNotExists([]byte(quotaBucket))
+		if err != nil {
+			return err
+		}
+
+		for kind, n := range counts {
+			key := []byte(day + "/" + kind)
+			if err = bucket.Put(key, quotaCount(decodeQuotaCount(bucket.Get(key))+n)); err != nil {
+				return err
+			}
+		}
+
+		if start, err := time.Parse(dayLayout, day); err == nil {
+			cutoff := []byte(start.AddDate(0, 0, -quotaDays).Format(dayLayout))
+			c := bucket.Cursor()
+			for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.First() {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// quotaUsage read request counts of the day.
+func (r *BoltRepository) quotaUsage(day string) (map[string]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	usage := make(map[string]int)
+	err := r.view(func(tx *bbolt.Tx) error {
+		bucket := r.buckets(tx).Bucket([]byte(quotaBucket))
+		if bucket == nil {
+			return nil
+		}
+		prefix := []byte(day + "/")
+		c := bucket.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			usage[string(k[len(prefix):])] = decodeQuotaCount(v)
+		}
+		return nil
+	})
+	return usage, err
+}
+
+func quotaCount(n int) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(n))
+	return buf
+}
+
+func decodeQuotaCount(v []byte) int {
+	if len(v) != 8 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(v))
+}
@@ -0,0 +1,155 @@
+package gphoto
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_tokenBucket(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := newTokenBucket(1, 4)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NoError(t, b.wait(canceled, 2, 0))
+	assert.Equal(t, context.Canceled, b.wait(canceled, 1, 2), "reserved tokens are left")
+	assert.NoError(t, b.wait(canceled, 2, 0), "the reserve is available without it")
+	assert.Equal(t, context.Canceled, b.wait(canceled, 1, 0), "bucket is empty")
+
+	now = now.Add(3 * time.Second)
+	assert.NoError(t, b.wait(canceled, 3, 0), "bucket is refilled")
+	now = now.Add(time.Hour)
+	assert.NoError(t, b.wait(canceled, 4, 0), "refill is limited by burst")
+	assert.Equal(t, context.Canceled, b.wait(canceled, 1, 0))
+}
+
+func Test_quotaLedger(t *testing.T) {
+	db, dir := newTempBolt(t)
+	defer os.RemoveAll(dir)
+	repo, err := NewBoltRepository(db)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	ledgers := map[string]quotaLedger{"bolt": newStoredLedger(repo), "memory": newMemoryLedger()}
+	for name, ledger := range ledgers {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, ledger.takeQuota("2020-05-01", quotaRead, 3))
+			assert.NoError(t, ledger.takeQuota("2020-05-01", quotaWrite, 3))
+			assert.NoError(t, ledger.takeQuota("2020-05-01", quotaRead, 3))
+			assert.Equal(t, ErrQuotaExceeded, ledger.takeQuota("2020-05-01", quotaRead, 3))
+			assert.NoError(t, ledger.takeQuota("2020-05-01", quotaRead, 0), "zero limit")
+
+			usage, err := ledger.quotaUsage("2020-05-01")
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]int{quotaRead: 3, quotaWrite: 1}, usage)
+
+			assert.NoError(t, ledger.takeQuota("2020-06-15", quotaRead, 3), "new day has its own budget")
+			usage, err = ledger.quotaUsage("2020-05-01")
+			assert.NoError(t, err)
+			assert.Empty(t, usage, "old days are dropped")
+		})
+	}
+}
+
+func Test_storedLedger(t *testing.T) {
+	db, dir := newTempBolt(t)
+	defer os.RemoveAll(dir)
+	repo, err := NewBoltRepository(db)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	ledger := newStoredLedger(repo)
+	ledger.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, ledger.takeQuota("2020-05-01", quotaRead, 0))
+	}
+	stored, err := repo.quotaUsage("2020-05-01")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{quotaRead: 1}, stored, "requests after the first one do not wait for the storage")
+
+	now = now.Add(quotaFlushInterval)
+	assert.NoError(t, ledger.takeQuota("2020-05-01", quotaWrite, 0))
+	stored, err = repo.quotaUsage("2020-05-01")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{quotaRead: 3, quotaWrite: 1}, stored, "counts are written every flush interval")
+
+	assert.NoError(t, ledger.takeQuota("2020-05-01", quotaRead, 0))
+	assert.NoError(t, ledger.flush())
+	restarted := newStoredLedger(repo)
+	assert.NoError(t, restarted.takeQuota("2020-05-01", quotaRead, 6))
+	assert.Equal(t, ErrQuotaExceeded, restarted.takeQuota("2020-05-01", quotaRead, 6), "stored counts are loaded")
+}
+
+func TestClient_rateLimits(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 1500)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/albums":
+			_, _ = w.Write([]byte(`{"albums":[{"id":"album"}]}`))
+		case "/content":
+			_, _ = w.Write(content)
+		}
+	}))
+	defer server.Close()
+
+	db, dir := newTempBolt(t)
+	defer os.RemoveAll(dir)
+	repo, err := NewBoltRepository(db)
+	require.NoError(t, err)
+
+	client, err := NewGoogleClient("id", "secret", "refresh",
+		WithEndpoints(server.URL, server.URL+"/token"),
+		WithRepository(repo),
+		WithRateLimits(RateLimits{DailyRequests: 3, InteractiveReserve: 0.5, DownloadBytesPerSecond: 1000}),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	batch := client.WithContext(WithPriority(context.Background(), PriorityBatch))
+	_, err = batch.GetAlbumList()
+	assert.NoError(t, err)
+	_, err = batch.GetAlbumList()
+	assert.Equal(t, ErrQuotaExceeded, err, "batch requests leave the reserve")
+
+	usage, err := client.Quota()
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.Reads)
+	assert.Equal(t, 2, usage.Remaining)
+	assert.Equal(t, 0, usage.BatchRemaining)
+
+	for i := 0; i < 2; i++ {
+		_, err = client.GetAlbumList()
+		assert.NoError(t, err)
+	}
+	_, err = client.GetAlbumList()
+	assert.Equal(t, ErrQuotaExceeded, err)
+
+	body, _, err := client.api.download(server.URL+"/content", 0)
+	require.NoError(t, err)
+	defer body.Close()
+	start := time.Now()
+	got, err := ioutil.ReadAll(body)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.True(t, time.Since(start) >= 400*time.Millisecond, "download above the burst is throttled")
+}
+
+func TestClient_Quota(t *testing.T) {
+	client := &Client{}
+	_, err := client.Quota()
+	assert.Equal(t, noLimitsErr, err)
+}
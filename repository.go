@@ -1,10 +1,9 @@
 package gphoto
 
 import (
-	"encoding/json"
+	"encoding/binary"
 	"errors"
-	"fmt"
-	"strconv"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	"go.etcd.io/bbolt"
@@ -12,24 +11,55 @@ import (
 
 const (
 	photoBucket   = "photo"
+	albumBucket   = "album"
+	metaBucket    = "meta"
+	accessBucket  = "access"
+	accountBucket = "account"
+	schemaKey     = "schema_version"
 	googlePhotoDB = "gphoto.db"
 )
 
-var albumNotExists = errors.New("album not exists")
+// ErrAlbumNotExists is returned by Repository for albums missing in the storage
+// and by Client for albums unknown to the api.
+var ErrAlbumNotExists = errors.New("album not exists")
 
 // BoltRepository is a bolt db repository implementation.
 type BoltRepository struct {
 	DB *bbolt.DB
+	// mu is held exclusively while DB is replaced by compaction.
+	mu sync.RWMutex
+	// keys encrypt stored values when set.
+	keys KeyProvider
+	// account namespace the buckets of a database shared by Manager accounts.
+	account string
 }
 
-func (r BoltRepository) close() error {
+// bucketSet hold repository buckets, it is implemented by *bbolt.Tx and *bbolt.Bucket.
+type bucketSet interface {
+	Bucket(name []byte) *bbolt.Bucket
+	CreateBucket(name []byte) (*bbolt.Bucket, error)
+	CreateBucketIfNotExists(name []byte) (*bbolt.Bucket, error)
+	DeleteBucket(name []byte) error
+}
+
+// Close close bolt db connection, a database shared by Manager accounts is left open.
+func (r *BoltRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.account != "" {
+		return nil
+	}
 	logrus.Debugln("bolt db connection closed")
 	return r.DB.Close()
 }
 
-// savePhotos save photos,received via api, into album bucket.
-func (r BoltRepository) savePhotos(album string, photos []*GooglePhoto) error {
-	tx, err := r.DB.Begin(true)
+// SavePhotos save photos,received via api, into album bucket.
+func (r *BoltRepository) SavePhotos(album string, photos []*GooglePhoto) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tx, err := r.begin()
 	if err != nil {
 		return err
 	}
@@ -37,7 +67,7 @@ func (r BoltRepository) savePhotos(album string, photos []*GooglePhoto) error {
 		_ = tx.Rollback()
 	}()
 
-	photoBucket := tx.Bucket([]byte(photoBucket))
+	photoBucket := r.buckets(tx).Bucket([]byte(photoBucket))
 
 	albumBucket, err := photoBucket.CreateBucketIfNotExists([]byte(album))
 	if err != nil {
@@ -49,21 +79,27 @@ func (r BoltRepository) savePhotos(album string, photos []*GooglePhoto) error {
 		if err != nil {
 			return err
 		}
-		if buf, err := json.Marshal(photo); err != nil {
+		if buf, err := r.marshal(photo); err != nil {
 			return err
-		} else if err := albumBucket.Put([]byte(strconv.FormatUint(photoID, 10)), buf); err != nil {
+		} else if err := albumBucket.Put(photoKey(photoID), buf); err != nil {
 			return err
 		}
 	}
+	if err = touchAlbum(r.buckets(tx), album); err != nil {
+		return err
+	}
 	logrus.WithFields(logrus.Fields{"album": album, "count": len(photos)}).Debugln("save album photo")
 	return tx.Commit()
 }
 
-// listPhotos fetch photos from album boltdb bucket.
-func (r BoltRepository) listPhotos(album string) ([]*GooglePhoto, error) {
+// ListPhotos fetch photos from album boltdb bucket.
+func (r *BoltRepository) ListPhotos(album string) ([]*GooglePhoto, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var items []*GooglePhoto
 
-	tx, err := r.DB.Begin(true)
+	tx, err := r.begin()
 	if err != nil {
 		return items, err
 	}
@@ -71,30 +107,36 @@ func (r BoltRepository) listPhotos(album string) ([]*GooglePhoto, error) {
 		_ = tx.Rollback()
 	}()
 
-	pBucket := tx.Bucket([]byte(photoBucket))
+	pBucket := r.buckets(tx).Bucket([]byte(photoBucket))
 	albumBucket := pBucket.Bucket([]byte(album))
 	if albumBucket == nil {
-		logrus.WithField("album", album).Debugln(albumNotExists)
-		return items, albumNotExists
+		logrus.WithField("album", album).Debugln(ErrAlbumNotExists)
+		return items, ErrAlbumNotExists
 	}
 
 	c := albumBucket.Cursor()
 	for k, v := c.First(); k != nil; k, v = c.Next() {
 		var photo GooglePhoto
-		if err = json.Unmarshal(v, &photo); err != nil {
+		if err = r.unmarshal(v, &photo); err != nil {
 			logrus.WithError(err).WithField("album", album).Errorln("unmarshal bolt value error")
 			return items, err
 		}
 		items = append(items, &photo)
 	}
+	if err = touchAlbum(r.buckets(tx), album); err != nil {
+		return items, err
+	}
 	err = tx.Commit()
 	logrus.WithFields(logrus.Fields{"album": album, "count": len(items)}).Debugln("get album photo from repo")
 	return items, err
 }
 
-// truncateAlbum truncate boltdb album bucket.
-func (r BoltRepository) truncateAlbum(album string) error {
-	tx, err := r.DB.Begin(true)
+// TruncateAlbum truncate boltdb album bucket.
+func (r *BoltRepository) TruncateAlbum(album string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tx, err := r.begin()
 	if err != nil {
 		return err
 	}
@@ -102,7 +144,7 @@ func (r BoltRepository) truncateAlbum(album string) error {
 		_ = tx.Rollback()
 	}()
 
-	pBucket := tx.Bucket([]byte(photoBucket))
+	pBucket := r.buckets(tx).Bucket([]byte(photoBucket))
 	albumBucket := pBucket.Bucket([]byte(album))
 	if albumBucket == nil {
 		return nil
@@ -110,23 +152,244 @@ func (r BoltRepository) truncateAlbum(album string) error {
 	if err = pBucket.DeleteBucket([]byte(album)); err != nil {
 		return err
 	}
+	if access := r.buckets(tx).Bucket([]byte(accessBucket)); access != nil {
+		if err = access.Delete([]byte(album)); err != nil {
+			return err
+		}
+	}
 	logrus.WithField("album", album).Debugln("truncate album photo")
 	return tx.Commit()
 }
 
-// NewBoltRepository make BoltRepository instance.
-func NewBoltRepository(DB *bbolt.DB) (*BoltRepository, error) {
-	var err error
-	err = DB.Update(func(tx *bbolt.Tx) error {
-		_, err = tx.CreateBucketIfNotExists([]byte(photoBucket))
+// UpdatePhoto replace cached records of the photo in every album bucket keeping their order.
+func (r *BoltRepository) UpdatePhoto(photo *GooglePhoto) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tx, err := r.begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	buf, err := r.marshal(photo)
+	if err != nil {
+		return err
+	}
+
+	var updated int
+	pBucket := r.buckets(tx).Bucket([]byte(photoBucket))
+	err = pBucket.ForEach(func(album, _ []byte) error {
+		albumBucket := pBucket.Bucket(album)
+		if albumBucket == nil {
+			return nil
+		}
+
+		var keys [][]byte
+		err := albumBucket.ForEach(func(k, v []byte) error {
+			var cached GooglePhoto
+			if err := r.unmarshal(v, &cached); err != nil {
+				return err
+			}
+			if cached.ID == photo.ID {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("create photo bucket: %s", err)
+			return err
+		}
+		for _, k := range keys {
+			if err := albumBucket.Put(k, buf); err != nil {
+				return err
+			}
 		}
+		updated += len(keys)
 		return nil
 	})
 	if err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{"photo": photo.ID, "count": updated}).Debugln("update cached photo")
+	return tx.Commit()
+}
+
+// SaveAlbums save albums, received via api, into album bucket replacing previous versions.
+func (r *BoltRepository) SaveAlbums(albums []*GoogleAlbum) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tx, err := r.begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	bucket, err := r.buckets(tx).CreateBucketIfNotExists([]byte(albumBucket))
+	if err != nil {
+		return err
+	}
+
+	for _, album := range albums {
+		if buf, err := r.marshal(album); err != nil {
+			return err
+		} else if err := bucket.Put([]byte(album.ID), buf); err != nil {
+			return err
+		}
+	}
+	logrus.WithField("count", len(albums)).Debugln("save albums")
+	return tx.Commit()
+}
+
+// ListAlbums fetch all albums from album bucket.
+func (r *BoltRepository) ListAlbums() ([]*GoogleAlbum, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var albums []*GoogleAlbum
+
+	err := r.view(func(tx *bbolt.Tx) error {
+		bucket := r.buckets(tx).Bucket([]byte(albumBucket))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var album GoogleAlbum
+			if err := r.unmarshal(v, &album); err != nil {
+				return err
+			}
+			albums = append(albums, &album)
+			return nil
+		})
+	})
+	return albums, err
+}
+
+// GetAlbum fetch album from album bucket.
+func (r *BoltRepository) GetAlbum(albumID string) (*GoogleAlbum, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var album *GoogleAlbum
+
+	err := r.view(func(tx *bbolt.Tx) error {
+		bucket := r.buckets(tx).Bucket([]byte(albumBucket))
+		if bucket == nil {
+			return ErrAlbumNotExists
+		}
+		v := bucket.Get([]byte(albumID))
+		if v == nil {
+			return ErrAlbumNotExists
+		}
+		album = new(GoogleAlbum)
+		return r.unmarshal(v, album)
+	})
+	return album, err
+}
+
+// begin start a write transaction, ErrAccountNotExists is returned once Manager removed the account.
+func (r *BoltRepository) begin() (*bbolt.Tx, error) {
+	tx, err := r.DB.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	if accountBuckets(tx, r.account) == nil {
+		_ = tx.Rollback()
+		return nil, ErrAccountNotExists
+	}
+	return tx, nil
+}
+
+// view run fn in a read transaction, see begin.
+func (r *BoltRepository) view(fn func(tx *bbolt.Tx) error) error {
+	return r.DB.View(func(tx *bbolt.Tx) error {
+		if accountBuckets(tx, r.account) == nil {
+			return ErrAccountNotExists
+		}
+		return fn(tx)
+	})
+}
+
+// update run fn in a write transaction, see begin.
+func (r *BoltRepository) update(fn func(tx *bbolt.Tx) error) error {
+	return r.DB.Update(func(tx *bbolt.Tx) error {
+		if accountBuckets(tx, r.account) == nil {
+			return ErrAccountNotExists
+		}
+		return fn(tx)
+	})
+}
+
+// buckets return the repository buckets, they are nested in the account bucket for namespaced repositories.
+func (r *BoltRepository) buckets(tx *bbolt.Tx) bucketSet {
+	return accountBuckets(tx, r.account)
+}
+
+// accountBuckets return buckets of the account, the database root is used for the empty one.
+// The account bucket is created by newBoltRepository, nil is returned if it is missing.
+func accountBuckets(tx *bbolt.Tx, account string) bucketSet {
+	if account == "" {
+		return tx
+	}
+	if accounts := tx.Bucket([]byte(accountBucket)); accounts != nil {
+		if b := accounts.Bucket([]byte(account)); b != nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// photoKey encode photo sequence number so bolt keeps photos in the saved order.
+func photoKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// NewBoltRepository make BoltRepository instance, the database is migrated to the current schema version.
+// ErrSchemaNewer is returned for databases written by a newer version of the package.
+func NewBoltRepository(DB *bbolt.DB) (*BoltRepository, error) {
+	return newBoltRepository(DB, nil, "")
+}
+
+// newBoltRepository migrate the database and bring its records to the current key of keys.
+// Buckets of a non empty account are kept in its own bucket, see Manager.
+func newBoltRepository(DB *bbolt.DB, keys KeyProvider, account string) (*BoltRepository, error) {
+	if err := migrateAccount(DB, account, boltMigrations); err == ErrSchemaNewer {
+		return nil, err
+	} else if err != nil {
 		logrus.WithError(err).Errorln(createRepoErr)
 		return nil, createRepoErr
 	}
-	return &BoltRepository{DB: DB}, nil
+	r := &BoltRepository{DB: DB, keys: keys, account: account}
+
+	stored, err := r.storedKeyID()
+	if err != nil {
+		logrus.WithError(err).Errorln(createRepoErr)
+		return nil, createRepoErr
+	}
+	if keys == nil {
+		if stored != "" {
+			logrus.Errorln(keyRequiredErr)
+			return nil, keyRequiredErr
+		}
+		return r, nil
+	}
+
+	current, _, err := keys.CurrentKey()
+	if err != nil {
+		logrus.WithError(err).Errorln(createRepoErr)
+		return nil, createRepoErr
+	}
+	if stored != current {
+		if _, err = r.rotateKey(); err != nil {
+			logrus.WithError(err).Errorln(rotateKeyErr)
+			return nil, rotateKeyErr
+		}
+	}
+	return r, nil
 }
@@ -0,0 +1,59 @@
+package gphoto_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ihippik/gphoto"
+	"github.com/ihippik/gphoto/gphototest"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+// tempDir create temporary directory removed by the returned cleanup.
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "gphoto")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return dir, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestBoltRepository_suite(t *testing.T) {
+	gphototest.RunRepositorySuite(t, func(t *testing.T) (gphoto.Repository, func()) {
+		dir, cleanup := tempDir(t)
+		db, err := bbolt.Open(filepath.Join(dir, "gphoto.db"), 0600, nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		repo, err := gphoto.NewBoltRepository(db)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		return repo, cleanup
+	})
+}
+
+func TestEncryptedBoltRepository_suite(t *testing.T) {
+	keys := &gphoto.KeyRing{Current: "key", Keys: map[string][]byte{"key": make([]byte, 32)}}
+	gphototest.RunRepositorySuite(t, func(t *testing.T) (gphoto.Repository, func()) {
+		dir, cleanup := tempDir(t)
+		db, err := bbolt.Open(filepath.Join(dir, "gphoto.db"), 0600, nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		repo, err := gphoto.NewEncryptedBoltRepository(db, keys)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		return repo, cleanup
+	})
+}
+
+func TestMemoryRepository_suite(t *testing.T) {
+	gphototest.RunRepositorySuite(t, func(t *testing.T) (gphoto.Repository, func()) {
+		return gphoto.NewMemoryRepository(0, 0, 0), nil
+	})
+}
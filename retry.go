@@ -0,0 +1,117 @@
+package gphoto
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetryPolicy describe retries of Google API requests failed with
+// a network error, 429 Too Many Requests or 5xx status.
+// Requests changing the library are retried on 429 only, as after other failures
+// they may have been applied.
+type RetryPolicy struct {
+	// MaxAttempts is a total number of attempts, values below 2 disable retries.
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts"`
+	// MinBackoff is a delay before the first retry, it doubles on every next one.
+	MinBackoff Duration `json:"min_backoff" yaml:"min_backoff"`
+	// MaxBackoff limit the delay between attempts.
+	MaxBackoff Duration `json:"max_backoff" yaml:"max_backoff"`
+}
+
+// backoff calculate delay before the attempt, Retry-After header takes precedence.
+// Both are limited by MaxBackoff.
+func (p RetryPolicy) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+			delay := time.Duration(seconds) * time.Second
+			if p.MaxBackoff > 0 && delay > time.Duration(p.MaxBackoff) {
+				delay = time.Duration(p.MaxBackoff)
+			}
+			return delay
+		}
+	}
+
+	delay := time.Duration(p.MinBackoff)
+	for i := 1; i < attempt && (p.MaxBackoff == 0 || delay < time.Duration(p.MaxBackoff)); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > time.Duration(p.MaxBackoff) {
+		delay = time.Duration(p.MaxBackoff)
+	}
+	return delay
+}
+
+// retryable check the response is worth another attempt.
+// Network errors and 5xx are ambiguous, so only requests safe to repeat are retried on them.
+func (g *googleApi) retryable(req *http.Request, res *http.Response, err error) bool {
+	if err == nil && res.StatusCode < http.StatusInternalServerError {
+		return res.StatusCode == http.StatusTooManyRequests
+	}
+	return g.idempotent(req)
+}
+
+// idempotent report whether repeating the request has no further effect,
+// POST requests are safe for the read-only search and the token refresh only.
+func (g *googleApi) idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	switch g.endpoint(req) {
+	case "mediaItems:search", "token":
+		return true
+	}
+	return false
+}
+
+// do send API request according to the retry policy.
+func (g *googleApi) do(req *http.Request) (*http.Response, error) {
+	return g.send(g.client, req)
+}
+
+// send request through the client according to the retry policy.
+func (g *googleApi) send(client *http.Client, req *http.Request) (*http.Response, error) {
+	if g.ctx != nil {
+		req = req.WithContext(g.ctx)
+	}
+	for attempt := 1; ; attempt++ {
+		if g.limiter != nil {
+			if err := g.limiter.wait(req, g.endpoint(req)); err != nil {
+				return nil, err
+			}
+		}
+		traced, span := g.startRequestSpan(req, attempt)
+		start := time.Now()
+		res, err := client.Do(traced)
+		g.observeAPICall(req, res, start)
+		endRequestSpan(span, res, err)
+		if attempt >= g.retry.MaxAttempts || !g.retryable(req, res, err) {
+			if err == nil && g.limiter != nil && g.endpoint(req) == "content" {
+				res.Body = g.limiter.throttle(req.Context(), res.Body)
+			}
+			return res, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return res, err
+		}
+
+		delay := g.retry.backoff(attempt, res)
+		fields := logrus.Fields{"url": req.URL.Path, "attempt": attempt, "delay": delay}
+		if err != nil {
+			logrus.WithError(err).WithFields(fields).Warnln("api request failed, retrying")
+		} else {
+			_ = res.Body.Close()
+			logrus.WithFields(fields).WithField("status", res.StatusCode).Warnln("api request failed, retrying")
+		}
+		time.Sleep(delay)
+
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
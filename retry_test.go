@@ -0,0 +1,110 @@
+package gphoto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: Duration(time.Second), MaxBackoff: Duration(5 * time.Second)}
+
+	assert.Equal(t, time.Second, policy.backoff(1, nil))
+	assert.Equal(t, 2*time.Second, policy.backoff(2, nil))
+	assert.Equal(t, 4*time.Second, policy.backoff(3, nil))
+	assert.Equal(t, 5*time.Second, policy.backoff(10, nil))
+
+	res := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	assert.Equal(t, 2*time.Second, policy.backoff(1, res))
+
+	res.Header.Set("Retry-After", "3600")
+	assert.Equal(t, 5*time.Second, policy.backoff(1, res), "Retry-After is limited by MaxBackoff")
+}
+
+func Test_googleApi_do(t *testing.T) {
+	tests := []struct {
+		name         string
+		endpoint     string
+		maxAttempts  int
+		statuses     []int
+		wantStatus   int
+		wantAttempts int
+	}{
+		{
+			name:         "retries disabled",
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retry until success",
+			maxAttempts:  3,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusOK},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+		},
+		{
+			name:         "attempts exhausted",
+			maxAttempts:  2,
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			wantStatus:   http.StatusBadGateway,
+			wantAttempts: 2,
+		},
+		{
+			name:         "client error is not retried",
+			maxAttempts:  3,
+			statuses:     []int{http.StatusNotFound, http.StatusOK},
+			wantStatus:   http.StatusNotFound,
+			wantAttempts: 1,
+		},
+		{
+			name:         "mutating request is not retried on server error",
+			endpoint:     "mediaItems:batchCreate",
+			maxAttempts:  3,
+			statuses:     []int{http.StatusInternalServerError, http.StatusOK},
+			wantStatus:   http.StatusInternalServerError,
+			wantAttempts: 1,
+		},
+		{
+			name:         "mutating request is retried on too many requests",
+			endpoint:     "albums/album:addEnrichment",
+			maxAttempts:  3,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusOK},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				body := make([]byte, 4)
+				n, _ := req.Body.Read(body)
+				assert.Equal(t, "body", string(body[:n]))
+				rw.WriteHeader(tt.statuses[attempts])
+				attempts++
+			}))
+			defer server.Close()
+
+			api := googleApi{
+				client:       server.Client(),
+				getAlbumsURL: server.URL + "/v1/albums",
+				retry:        RetryPolicy{MaxAttempts: tt.maxAttempts, MinBackoff: Duration(time.Millisecond)},
+			}
+			endpoint := tt.endpoint
+			if endpoint == "" {
+				endpoint = "mediaItems:search"
+			}
+			req, err := http.NewRequest("POST", server.URL+"/v1/"+endpoint, strings.NewReader("body"))
+			assert.NoError(t, err)
+			res, err := api.do(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			assert.Equal(t, tt.wantAttempts, attempts)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package gphoto
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Media types of SearchFilter.
+const (
+	MediaTypeAll   = "ALL_MEDIA"
+	MediaTypePhoto = "PHOTO"
+	MediaTypeVideo = "VIDEO"
+)
+
+var searchMediaErr = errors.New("search media error")
+
+// SearchFilter narrow library search, zero values are ignored.
+type SearchFilter struct {
+	// From and To limit media creation date, both dates are inclusive.
+	From time.Time
+	To   time.Time
+	// MediaType is one of MediaTypeAll, MediaTypePhoto or MediaTypeVideo.
+	MediaType string
+	// Categories are content categories, e.g. LANDSCAPES or PETS.
+	Categories []string
+	// Favorites return only media marked as favorite.
+	Favorites bool
+}
+
+// filters convert filter into api search filters.
+func (f SearchFilter) filters() *searchFilters {
+	var filters searchFilters
+	empty := true
+
+	if !f.From.IsZero() || !f.To.IsZero() {
+		dateRange := searchDateRange{StartDate: searchDate{Year: 1}, EndDate: searchDate{Year: 9999, Month: 12, Day: 31}}
+		if !f.From.IsZero() {
+			dateRange.StartDate = searchDate{Year: f.From.Year(), Month: int(f.From.Month()), Day: f.From.Day()}
+		}
+		if !f.To.IsZero() {
+			dateRange.EndDate = searchDate{Year: f.To.Year(), Month: int(f.To.Month()), Day: f.To.Day()}
+		}
+		filters.DateFilter = &struct {
+			Ranges []searchDateRange `json:"ranges"`
+		}{Ranges: []searchDateRange{dateRange}}
+		empty = false
+	}
+	if len(f.Categories) > 0 {
+		filters.ContentFilter = &struct {
+			IncludedContentCategories []string `json:"includedContentCategories"`
+		}{IncludedContentCategories: f.Categories}
+		empty = false
+	}
+	if f.MediaType != "" {
+		filters.MediaTypeFilter = &struct {
+			MediaTypes []string `json:"mediaTypes"`
+		}{MediaTypes: []string{f.MediaType}}
+		empty = false
+	}
+	if f.Favorites {
+		filters.FeatureFilter = &struct {
+			IncludedFeatures []string `json:"includedFeatures"`
+		}{IncludedFeatures: []string{"FAVORITES"}}
+		empty = false
+	}
+
+	if empty {
+		return nil
+	}
+	return &filters
+}
+
+// Search fetch library media items matching the filter.
+func (c *Client) Search(filter SearchFilter) (photos []*GooglePhoto, err error) {
+	c, _, end := c.startSpan("gphoto.Search")
+	defer func() { end(err, Attr(AttrItemCount, len(photos))) }()
+
+	err = c.withAuth(func(accessToken string) error {
+		var err error
+		photos, err = c.api.searchMedia(accessToken, filter)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return photos, err
+	} else if err != nil {
+		logrus.WithError(err).Errorln(searchMediaErr)
+		return photos, searchMediaErr
+	}
+	return photos, nil
+}
@@ -0,0 +1,44 @@
+package gphoto
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchFilter_filters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   string
+	}{
+		{
+			name: "empty",
+			want: `null`,
+		},
+		{
+			name: "date range and type",
+			filter: SearchFilter{
+				From:      time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC),
+				To:        time.Date(2019, 5, 31, 0, 0, 0, 0, time.UTC),
+				MediaType: MediaTypeVideo,
+			},
+			want: `{"dateFilter":{"ranges":[{"startDate":{"year":2019,"month":5,"day":1},"endDate":{"year":2019,"month":5,"day":31}}]},` +
+				`"mediaTypeFilter":{"mediaTypes":["VIDEO"]}}`,
+		},
+		{
+			name:   "categories and favorites",
+			filter: SearchFilter{Categories: []string{"PETS"}, Favorites: true},
+			want:   `{"contentFilter":{"includedContentCategories":["PETS"]},"featureFilter":{"includedFeatures":["FAVORITES"]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.filter.filters())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package gphoto
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	sharedAlbumsErr = errors.New("get shared albums error")
+	joinAlbumErr    = errors.New("join shared album error")
+	leaveAlbumErr   = errors.New("leave shared album error")
+	shareAlbumErr   = errors.New("share album error")
+	unshareAlbumErr = errors.New("unshare album error")
+)
+
+// ListSharedAlbums fetch all albums shared with the user, including the ones not joined yet.
+func (c *Client) ListSharedAlbums() ([]*GoogleAlbum, error) {
+	var albums []*GoogleAlbum
+
+	err := c.withAuth(func(accessToken string) error {
+		var err error
+		albums, err = c.api.listSharedAlbums(accessToken)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return albums, err
+	} else if err != nil {
+		logrus.WithError(err).Errorln(sharedAlbumsErr)
+		return albums, sharedAlbumsErr
+	}
+
+	if err = c.repo.SaveAlbums(albums); err != nil {
+		logrus.WithError(err).Errorln(saveErr)
+		return albums, saveErr
+	}
+	return albums, nil
+}
+
+// JoinSharedAlbum join shared album on behalf of the user by share token.
+func (c *Client) JoinSharedAlbum(shareToken string) (*GoogleAlbum, error) {
+	var album *GoogleAlbum
+
+	err := c.withAuth(func(accessToken string) error {
+		var err error
+		album, err = c.api.joinSharedAlbum(accessToken, shareToken)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return nil, err
+	} else if err != nil {
+		logrus.WithError(err).Errorln(joinAlbumErr)
+		return nil, joinAlbumErr
+	}
+
+	if err = c.repo.SaveAlbums([]*GoogleAlbum{album}); err != nil {
+		logrus.WithError(err).Errorln(saveErr)
+		return album, saveErr
+	}
+	return album, nil
+}
+
+// LeaveSharedAlbum leave previously joined shared album by share token.
+func (c *Client) LeaveSharedAlbum(shareToken string) error {
+	err := c.withAuth(func(accessToken string) error {
+		return c.api.leaveSharedAlbum(accessToken, shareToken)
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return err
+	} else if err != nil {
+		logrus.WithError(err).Errorln(leaveAlbumErr)
+		return leaveAlbumErr
+	}
+
+	albums, err := c.repo.ListAlbums()
+	if err != nil {
+		logrus.WithError(err).Errorln(saveErr)
+		return saveErr
+	}
+	for _, album := range albums {
+		if album.ShareInfo != nil && album.ShareInfo.ShareToken == shareToken {
+			album.ShareInfo.IsJoined = false
+			if err = c.repo.SaveAlbums([]*GoogleAlbum{album}); err != nil {
+				logrus.WithError(err).Errorln(saveErr)
+				return saveErr
+			}
+		}
+	}
+	return nil
+}
+
+// ShareAlbum mark album owned by the user as shared and return its sharing state.
+func (c *Client) ShareAlbum(albumID string, options SharedAlbumOptions) (*ShareInfo, error) {
+	var shareInfo *ShareInfo
+
+	err := c.withAuth(func(accessToken string) error {
+		var err error
+		shareInfo, err = c.api.shareAlbum(accessToken, albumID, options)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return nil, err
+	} else if err != nil {
+		logrus.WithError(err).WithField("album", albumID).Errorln(shareAlbumErr)
+		return nil, shareAlbumErr
+	}
+
+	return shareInfo, c.updateCachedShareInfo(albumID, shareInfo)
+}
+
+// UnshareAlbum mark album owned by the user as private.
+func (c *Client) UnshareAlbum(albumID string) error {
+	err := c.withAuth(func(accessToken string) error {
+		return c.api.unshareAlbum(accessToken, albumID)
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return err
+	} else if err != nil {
+		logrus.WithError(err).WithField("album", albumID).Errorln(unshareAlbumErr)
+		return unshareAlbumErr
+	}
+
+	return c.updateCachedShareInfo(albumID, nil)
+}
+
+// updateCachedShareInfo update sharing state of the cached album, missing album is ignored.
+func (c *Client) updateCachedShareInfo(albumID string, shareInfo *ShareInfo) error {
+	album, err := c.repo.GetAlbum(albumID)
+	if err == ErrAlbumNotExists {
+		return nil
+	} else if err != nil {
+		logrus.WithError(err).WithField("album", albumID).Errorln(saveErr)
+		return saveErr
+	}
+
+	album.ShareInfo = shareInfo
+	if err = c.repo.SaveAlbums([]*GoogleAlbum{album}); err != nil {
+		logrus.WithError(err).WithField("album", albumID).Errorln(saveErr)
+		return saveErr
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package gphoto
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_SharedAlbums(t *testing.T) {
+	api := new(MockedApi)
+	c, dir := newBoltClient(t, api)
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	shared := &GoogleAlbum{ID: "shared", ShareInfo: &ShareInfo{ShareToken: "token", IsJoined: true}}
+	owned := &GoogleAlbum{ID: "owned", Title: "Trip"}
+
+	t.Run("list shared albums", func(t *testing.T) {
+		api.On("listSharedAlbums", "ACCESS_TOKEN").Return([]*GoogleAlbum{shared}, nil).Once()
+
+		albums, err := c.ListSharedAlbums()
+		assert.NoError(t, err)
+		assert.Equal(t, []*GoogleAlbum{shared}, albums)
+
+		cached, err := c.repo.GetAlbum("shared")
+		assert.NoError(t, err)
+		assert.Equal(t, shared, cached)
+	})
+
+	t.Run("leave shared album", func(t *testing.T) {
+		api.On("leaveSharedAlbum", "ACCESS_TOKEN", "token").Return(nil).Once()
+
+		assert.NoError(t, c.LeaveSharedAlbum("token"))
+		cached, err := c.repo.GetAlbum("shared")
+		assert.NoError(t, err)
+		assert.False(t, cached.ShareInfo.IsJoined)
+	})
+
+	t.Run("join shared album", func(t *testing.T) {
+		api.On("joinSharedAlbum", "ACCESS_TOKEN", "token").Return(shared, nil).Once()
+
+		album, err := c.JoinSharedAlbum("token")
+		assert.NoError(t, err)
+		assert.Equal(t, shared, album)
+		cached, err := c.repo.GetAlbum("shared")
+		assert.NoError(t, err)
+		assert.True(t, cached.ShareInfo.IsJoined)
+	})
+
+	t.Run("share and unshare album", func(t *testing.T) {
+		assert.NoError(t, c.repo.SaveAlbums([]*GoogleAlbum{owned}))
+		shareInfo := &ShareInfo{ShareToken: "owned-token", IsOwned: true}
+		api.On("shareAlbum", "ACCESS_TOKEN", "owned", SharedAlbumOptions{IsCommentable: true}).Return(shareInfo, nil).Once()
+		api.On("unshareAlbum", "ACCESS_TOKEN", "owned").Return(nil).Once()
+
+		got, err := c.ShareAlbum("owned", SharedAlbumOptions{IsCommentable: true})
+		assert.NoError(t, err)
+		assert.Equal(t, shareInfo, got)
+		cached, err := c.repo.GetAlbum("owned")
+		assert.NoError(t, err)
+		assert.Equal(t, shareInfo, cached.ShareInfo)
+
+		assert.NoError(t, c.UnshareAlbum("owned"))
+		cached, err = c.repo.GetAlbum("owned")
+		assert.NoError(t, err)
+		assert.Nil(t, cached.ShareInfo)
+	})
+
+	t.Run("share not cached album", func(t *testing.T) {
+		api.On("shareAlbum", "ACCESS_TOKEN", "unknown", SharedAlbumOptions{}).Return(&ShareInfo{}, nil).Once()
+
+		_, err := c.ShareAlbum("unknown", SharedAlbumOptions{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("join fail", func(t *testing.T) {
+		api.On("joinSharedAlbum", "ACCESS_TOKEN", "bad").Return(nil, someErr).Once()
+
+		_, err := c.JoinSharedAlbum("bad")
+		assert.Equal(t, joinAlbumErr, err)
+	})
+
+	api.AssertExpectations(t)
+}
@@ -0,0 +1,364 @@
+package gphoto
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+// Cache snapshot is a JSON Lines stream: a header, album, photo and sync records,
+// and a trailer with the number of records and SHA-256 of all preceding lines.
+const (
+	snapshotFormat  = "gphoto-cache"
+	snapshotVersion = 1
+
+	recordHeader  = "header"
+	recordAlbum   = "album"
+	recordPhoto   = "photo"
+	recordSync    = "sync"
+	recordTrailer = "trailer"
+)
+
+var (
+	snapshotErr = errors.New("invalid cache snapshot")
+	exportErr   = errors.New("cache export error")
+	importErr   = errors.New("cache import error")
+)
+
+// cacheSnapshotter is implemented by repositories able to dump and restore their content.
+type cacheSnapshotter interface {
+	exportSnapshot(w *snapshotWriter) error
+	importSnapshot(s *snapshot) error
+}
+
+// snapshotRecord is a single line of the snapshot.
+type snapshotRecord struct {
+	Type      string       `json:"type"`
+	Format    string       `json:"format,omitempty"`
+	Version   int          `json:"version,omitempty"`
+	CreatedAt *time.Time   `json:"createdAt,omitempty"`
+	Album     *GoogleAlbum `json:"album,omitempty"`
+	AlbumID   string       `json:"albumId,omitempty"`
+	Photo     *GooglePhoto `json:"photo,omitempty"`
+	Sync      *SyncRecord  `json:"sync,omitempty"`
+	Count     int          `json:"count,omitempty"`
+	SHA256    string       `json:"sha256,omitempty"`
+}
+
+// snapshot is a verified snapshot content, photos keep their order within albums.
+type snapshot struct {
+	albums      []*GoogleAlbum
+	photos      map[string][]*GooglePhoto
+	albumOrder  []string
+	syncRecords []*SyncRecord
+}
+
+// snapshotWriter write records hashing every line.
+type snapshotWriter struct {
+	w     io.Writer
+	hash  hash.Hash
+	count int
+}
+
+func newSnapshotWriter(w io.Writer) *snapshotWriter {
+	return &snapshotWriter{w: w, hash: sha256.New()}
+}
+
+func (s *snapshotWriter) write(record *snapshotRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err = s.w.Write(line); err != nil {
+		return err
+	}
+	_, _ = s.hash.Write(line)
+	s.count++
+	return nil
+}
+
+func (s *snapshotWriter) album(album *GoogleAlbum) error {
+	return s.write(&snapshotRecord{Type: recordAlbum, Album: album})
+}
+
+func (s *snapshotWriter) photo(albumID string, photo *GooglePhoto) error {
+	return s.write(&snapshotRecord{Type: recordPhoto, AlbumID: albumID, Photo: photo})
+}
+
+func (s *snapshotWriter) syncRecord(record *SyncRecord) error {
+	return s.write(&snapshotRecord{Type: recordSync, Sync: record})
+}
+
+// ExportCache write cached albums, photos and sync records as a versioned JSON Lines snapshot.
+func (c *Client) ExportCache(w io.Writer) error {
+	snapshotter, ok := c.repo.(cacheSnapshotter)
+	if !ok {
+		return cacheNotSupportedErr
+	}
+
+	buf := bufio.NewWriter(w)
+	sw := newSnapshotWriter(buf)
+	now := time.Now().UTC()
+	err := sw.write(&snapshotRecord{Type: recordHeader, Format: snapshotFormat, Version: snapshotVersion, CreatedAt: &now})
+	if err == nil {
+		err = snapshotter.exportSnapshot(sw)
+	}
+	if err == nil {
+		err = sw.write(&snapshotRecord{
+			Type:   recordTrailer,
+			Count:  sw.count - 1,
+			SHA256: hex.EncodeToString(sw.hash.Sum(nil)),
+		})
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		logrus.WithError(err).Errorln(exportErr)
+		return exportErr
+	}
+	logrus.WithField("count", sw.count-2).Debugln("cache exported")
+	return nil
+}
+
+// ImportCache verify snapshot written by ExportCache and load it into the cache.
+// Albums of the snapshot replace the cached ones, nothing is changed if the snapshot is invalid.
+func (c *Client) ImportCache(r io.Reader) error {
+	snapshotter, ok := c.repo.(cacheSnapshotter)
+	if !ok {
+		return cacheNotSupportedErr
+	}
+
+	s, err := readSnapshot(r)
+	if err != nil {
+		logrus.WithError(err).Errorln(snapshotErr)
+		return snapshotErr
+	}
+	if err = snapshotter.importSnapshot(s); err != nil {
+		logrus.WithError(err).Errorln(importErr)
+		return importErr
+	}
+	logrus.WithFields(logrus.Fields{
+		"albums":      len(s.albums),
+		"photoAlbums": len(s.albumOrder),
+		"sync":        len(s.syncRecords),
+	}).Debugln("cache imported")
+	return nil
+}
+
+// readSnapshot read the whole snapshot checking its header and trailer.
+func readSnapshot(r io.Reader) (*snapshot, error) {
+	var (
+		s       = &snapshot{photos: make(map[string][]*GooglePhoto)}
+		br      = bufio.NewReader(r)
+		sum     = sha256.New()
+		count   int
+		header  bool
+		trailer *snapshotRecord
+	)
+
+	for lineNo := 1; ; lineNo++ {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if trailer != nil {
+			return nil, fmt.Errorf("line %d: data after trailer", lineNo)
+		}
+
+		var record snapshotRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err)
+		}
+		if !header && record.Type != recordHeader {
+			return nil, errors.New("header is missing")
+		}
+
+		switch record.Type {
+		case recordHeader:
+			if header {
+				return nil, fmt.Errorf("line %d: duplicate header", lineNo)
+			}
+			if record.Format != snapshotFormat {
+				return nil, fmt.Errorf("unknown format %q", record.Format)
+			}
+			if record.Version < 1 || record.Version > snapshotVersion {
+				return nil, errors.New("unsupported version " + strconv.Itoa(record.Version))
+			}
+			header = true
+		case recordAlbum:
+			if record.Album == nil || record.Album.ID == "" {
+				return nil, fmt.Errorf("line %d: album without id", lineNo)
+			}
+			s.albums = append(s.albums, record.Album)
+		case recordPhoto:
+			if record.Photo == nil || record.AlbumID == "" {
+				return nil, fmt.Errorf("line %d: photo without album", lineNo)
+			}
+			if _, ok := s.photos[record.AlbumID]; !ok {
+				s.albumOrder = append(s.albumOrder, record.AlbumID)
+			}
+			s.photos[record.AlbumID] = append(s.photos[record.AlbumID], record.Photo)
+		case recordSync:
+			if record.Sync == nil || record.Sync.MediaID == "" {
+				return nil, fmt.Errorf("line %d: sync record without media id", lineNo)
+			}
+			s.syncRecords = append(s.syncRecords, record.Sync)
+		case recordTrailer:
+			trailer = &record
+			continue
+		default:
+			return nil, fmt.Errorf("line %d: unknown record type %q", lineNo, record.Type)
+		}
+
+		_, _ = sum.Write(line)
+		if record.Type != recordHeader {
+			count++
+		}
+	}
+
+	switch {
+	case !header:
+		return nil, errors.New("snapshot is empty")
+	case trailer == nil:
+		return nil, errors.New("trailer is missing, snapshot is truncated")
+	case trailer.Count != count:
+		return nil, fmt.Errorf("trailer count %d does not match %d records", trailer.Count, count)
+	case trailer.SHA256 != hex.EncodeToString(sum.Sum(nil)):
+		return nil, errors.New("checksum mismatch")
+	}
+	return s, nil
+}
+
+// exportSnapshot write albums, album photos and sync records of a single read transaction.
+func (r *BoltRepository) exportSnapshot(w *snapshotWriter) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.view(func(tx *bbolt.Tx) error {
+		if bucket := r.buckets(tx).Bucket([]byte(albumBucket)); bucket != nil {
+			err := bucket.ForEach(func(k, v []byte) error {
+				var album GoogleAlbum
+				if err := r.unmarshal(v, &album); err != nil {
+					return err
+				}
+				return w.album(&album)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if pBucket := r.buckets(tx).Bucket([]byte(photoBucket)); pBucket != nil {
+			err := pBucket.ForEach(func(album, _ []byte) error {
+				albumBucket := pBucket.Bucket(album)
+				if albumBucket == nil {
+					return nil
+				}
+				return albumBucket.ForEach(func(k, v []byte) error {
+					var photo GooglePhoto
+					if err := r.unmarshal(v, &photo); err != nil {
+						return err
+					}
+					return w.photo(string(album), &photo)
+				})
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if bucket := r.buckets(tx).Bucket([]byte(syncBucket)); bucket != nil {
+			return bucket.ForEach(func(k, v []byte) error {
+				var record SyncRecord
+				if err := r.unmarshal(v, &record); err != nil {
+					return err
+				}
+				return w.syncRecord(&record)
+			})
+		}
+		return nil
+	})
+}
+
+// importSnapshot load snapshot in a single transaction, photos of the snapshot albums are replaced.
+func (r *BoltRepository) importSnapshot(s *snapshot) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.update(func(tx *bbolt.Tx) error {
+		bucket, err := r.buckets(tx).CreateBucketIfNotExists([]byte(albumBucket))
+		if err != nil {
+			return err
+		}
+		for _, album := range s.albums {
+			buf, err := r.marshal(album)
+			if err != nil {
+				return err
+			}
+			if err = bucket.Put([]byte(album.ID), buf); err != nil {
+				return err
+			}
+		}
+
+		pBucket, err := r.buckets(tx).CreateBucketIfNotExists([]byte(photoBucket))
+		if err != nil {
+			return err
+		}
+		for _, album := range s.albumOrder {
+			if pBucket.Bucket([]byte(album)) != nil {
+				if err = pBucket.DeleteBucket([]byte(album)); err != nil {
+					return err
+				}
+			}
+			albumBucket, err := pBucket.CreateBucket([]byte(album))
+			if err != nil {
+				return err
+			}
+			for _, photo := range s.photos[album] {
+				seq, err := albumBucket.NextSequence()
+				if err != nil {
+					return err
+				}
+				buf, err := r.marshal(photo)
+				if err != nil {
+					return err
+				}
+				if err = albumBucket.Put(photoKey(seq), buf); err != nil {
+					return err
+				}
+			}
+			if err = touchAlbum(r.buckets(tx), album); err != nil {
+				return err
+			}
+		}
+
+		sBucket, err := r.buckets(tx).CreateBucketIfNotExists([]byte(syncBucket))
+		if err != nil {
+			return err
+		}
+		for _, record := range s.syncRecords {
+			buf, err := r.marshal(record)
+			if err != nil {
+				return err
+			}
+			if err = sBucket.Put([]byte(syncKey(record.AlbumID, record.MediaID)), buf); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
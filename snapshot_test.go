@@ -0,0 +1,99 @@
+package gphoto
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ExportImportCache(t *testing.T) {
+	source, dir := newBoltClient(t, new(MockedApi))
+	defer os.RemoveAll(dir)
+	defer source.Close()
+
+	photos := make([]*GooglePhoto, 0, 12)
+	for i := 0; i < 12; i++ {
+		photos = append(photos, &GooglePhoto{ID: fmt.Sprint(i), Filename: fmt.Sprintf("IMG_%d.jpg", i)})
+	}
+	albums := []*GoogleAlbum{{ID: "album", Title: "Album"}, {ID: "shared", ShareInfo: &ShareInfo{ShareToken: "token"}}}
+	record := &SyncRecord{MediaID: "0", Path: "IMG_0.jpg", Size: 5, FetchedAt: time.Date(2019, 7, 14, 0, 0, 0, 0, time.UTC)}
+	assert.NoError(t, source.repo.SaveAlbums(albums))
+	assert.NoError(t, source.repo.SavePhotos("album", photos))
+	assert.NoError(t, source.repo.(syncStore).saveSyncRecord(record))
+
+	var snapshot bytes.Buffer
+	assert.NoError(t, source.ExportCache(&snapshot))
+	lines := strings.Split(strings.TrimSpace(snapshot.String()), "\n")
+	assert.Len(t, lines, 1+len(albums)+len(photos)+1+1)
+
+	t.Run("round trip", func(t *testing.T) {
+		target, dir := newBoltClient(t, new(MockedApi))
+		defer os.RemoveAll(dir)
+		defer target.Close()
+		assert.NoError(t, target.repo.SavePhotos("album", []*GooglePhoto{{ID: "stale"}}))
+
+		assert.NoError(t, target.ImportCache(bytes.NewReader(snapshot.Bytes())))
+
+		got, err := target.repo.ListPhotos("album")
+		assert.NoError(t, err)
+		assert.Equal(t, photos, got)
+		gotAlbums, err := target.repo.ListAlbums()
+		assert.NoError(t, err)
+		assert.Equal(t, albums, gotAlbums)
+		records, err := target.repo.(syncStore).syncRecords()
+		assert.NoError(t, err)
+		assert.Equal(t, []*SyncRecord{record}, records)
+	})
+
+	tests := []struct {
+		name     string
+		snapshot string
+	}{
+		{
+			name:     "tampered record",
+			snapshot: strings.Replace(snapshot.String(), "IMG_3.jpg", "IMG_4.jpg", 1),
+		},
+		{
+			name:     "truncated",
+			snapshot: strings.Join(lines[:len(lines)-1], "\n") + "\n",
+		},
+		{
+			name:     "data after trailer",
+			snapshot: snapshot.String() + lines[1] + "\n",
+		},
+		{
+			name:     "missing header",
+			snapshot: strings.Join(lines[1:], "\n") + "\n",
+		},
+		{
+			name:     "newer version",
+			snapshot: strings.Replace(snapshot.String(), `"version":1`, `"version":2`, 1),
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, dir := newBoltClient(t, new(MockedApi))
+			defer os.RemoveAll(dir)
+			defer target.Close()
+
+			assert.Equal(t, snapshotErr, target.ImportCache(strings.NewReader(tt.snapshot)))
+			albums, err := target.repo.ListAlbums()
+			assert.NoError(t, err)
+			assert.Empty(t, albums, "nothing is imported")
+		})
+	}
+}
+
+func TestClient_ExportCacheUnsupportedRepo(t *testing.T) {
+	c := &Client{api: new(MockedApi), repo: new(MockedRepo)}
+	assert.Equal(t, cacheNotSupportedErr, c.ExportCache(new(bytes.Buffer)))
+	assert.Equal(t, cacheNotSupportedErr, c.ImportCache(new(bytes.Buffer)))
+}
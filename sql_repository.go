@@ -0,0 +1,344 @@
+package gphoto
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SQLDialect select placeholder syntax of the database driver.
+type SQLDialect int
+
+// Supported SQL dialects.
+const (
+	DialectSQLite SQLDialect = iota
+	DialectPostgres
+)
+
+// sqlMigrations upgrade schema step by step, migration N is stored as version N+1.
+// Applied migrations must never be changed, add a new one instead.
+var sqlMigrations = [][]string{
+	{
+		`CREATE TABLE albums (
+			id    TEXT PRIMARY KEY,
+			title TEXT NOT NULL,
+			data  TEXT NOT NULL
+		)`,
+		`CREATE TABLE media_items (
+			id        TEXT PRIMARY KEY,
+			filename  TEXT NOT NULL,
+			mime_type TEXT NOT NULL,
+			data      TEXT NOT NULL
+		)`,
+		`CREATE TABLE album_items (
+			album_id TEXT    NOT NULL,
+			position INTEGER NOT NULL,
+			media_id TEXT    NOT NULL REFERENCES media_items (id),
+			PRIMARY KEY (album_id, position)
+		)`,
+		`CREATE INDEX album_items_media_id ON album_items (media_id)`,
+	},
+	{
+		`CREATE TABLE cached_albums (
+			album_id TEXT PRIMARY KEY
+		)`,
+		`INSERT INTO cached_albums (album_id) SELECT DISTINCT album_id FROM album_items`,
+	},
+}
+
+// SQLRepository is a database/sql repository implementation.
+// Albums and media items are stored once, album_items keep album membership and order,
+// cached_albums keep albums with saved photos, even an empty list of them.
+type SQLRepository struct {
+	DB      *sql.DB
+	dialect SQLDialect
+}
+
+// NewSQLRepository make SQLRepository instance and migrate the database schema.
+// The database driver must be registered by the caller.
+func NewSQLRepository(db *sql.DB, dialect SQLDialect) (*SQLRepository, error) {
+	r := &SQLRepository{DB: db, dialect: dialect}
+	if err := r.migrate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// migrate apply migrations missing in the schema_migrations table.
+func (r *SQLRepository) migrate() error {
+	_, err := r.DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	if err = r.DB.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+	if version > len(sqlMigrations) {
+		logrus.WithFields(logrus.Fields{"version": version, "supported": len(sqlMigrations)}).Errorln(ErrSchemaNewer)
+		return ErrSchemaNewer
+	}
+
+	for ; version < len(sqlMigrations); version++ {
+		err = r.inTx(func(tx *sql.Tx) error {
+			for _, stmt := range sqlMigrations[version] {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(r.rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), version+1)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		logrus.WithField("version", version+1).Debugln("sql schema migrated")
+	}
+	return nil
+}
+
+// Close close database connection.
+func (r *SQLRepository) Close() error {
+	logrus.Debugln("sql db connection closed")
+	return r.DB.Close()
+}
+
+// SavePhotos upsert photos and append them to the album.
+func (r *SQLRepository) SavePhotos(album string, photos []*GooglePhoto) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(r.rebind(`INSERT INTO cached_albums (album_id) VALUES (?) ON CONFLICT (album_id) DO NOTHING`), album)
+		if err != nil {
+			return err
+		}
+
+		var position int64
+		err = tx.QueryRow(r.rebind(`SELECT COALESCE(MAX(position), 0) FROM album_items WHERE album_id = ?`), album).
+			Scan(&position)
+		if err != nil {
+			return err
+		}
+
+		for _, photo := range photos {
+			if err := r.upsertPhoto(tx, photo); err != nil {
+				return err
+			}
+			position++
+			_, err := tx.Exec(
+				r.rebind(`INSERT INTO album_items (album_id, position, media_id) VALUES (?, ?, ?)`),
+				album, position, photo.ID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		logrus.WithFields(logrus.Fields{"album": album, "count": len(photos)}).Debugln("save album photo")
+		return nil
+	})
+}
+
+// ListPhotos fetch photos of the album in the saved order.
+func (r *SQLRepository) ListPhotos(album string) ([]*GooglePhoto, error) {
+	var cached int
+	err := r.DB.QueryRow(r.rebind(`SELECT COUNT(*) FROM cached_albums WHERE album_id = ?`), album).Scan(&cached)
+	if err != nil {
+		return nil, err
+	}
+	if cached == 0 {
+		logrus.WithField("album", album).Debugln(ErrAlbumNotExists)
+		return nil, ErrAlbumNotExists
+	}
+
+	photos := make([]*GooglePhoto, 0)
+	rows, err := r.DB.Query(r.rebind(`
+		SELECT m.data FROM album_items a
+		JOIN media_items m ON m.id = a.media_id
+		WHERE a.album_id = ?
+		ORDER BY a.position`), album)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var photo GooglePhoto
+		if err := json.Unmarshal([]byte(data), &photo); err != nil {
+			return nil, err
+		}
+		photos = append(photos, &photo)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// TruncateAlbum drop album membership and media items left without an album.
+func (r *SQLRepository) TruncateAlbum(album string) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(r.rebind(`DELETE FROM cached_albums WHERE album_id = ?`), album); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(r.rebind(`DELETE FROM album_items WHERE album_id = ?`), album); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM media_items WHERE id NOT IN (SELECT media_id FROM album_items)`)
+		if err != nil {
+			return err
+		}
+		logrus.WithField("album", album).Debugln("truncate album photo")
+		return nil
+	})
+}
+
+// UpdatePhoto replace stored media item, album membership is kept.
+func (r *SQLRepository) UpdatePhoto(photo *GooglePhoto) error {
+	data, err := json.Marshal(photo)
+	if err != nil {
+		return err
+	}
+	_, err = r.DB.Exec(
+		r.rebind(`UPDATE media_items SET filename = ?, mime_type = ?, data = ? WHERE id = ?`),
+		photo.Filename, photo.MimeType, string(data), photo.ID,
+	)
+	return err
+}
+
+// SaveAlbums upsert albums.
+func (r *SQLRepository) SaveAlbums(albums []*GoogleAlbum) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		for _, album := range albums {
+			data, err := json.Marshal(album)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(r.rebind(`
+				INSERT INTO albums (id, title, data) VALUES (?, ?, ?)
+				ON CONFLICT (id) DO UPDATE SET title = excluded.title, data = excluded.data`),
+				album.ID, album.Title, string(data),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		logrus.WithField("count", len(albums)).Debugln("save albums")
+		return nil
+	})
+}
+
+// ListAlbums fetch all albums ordered by id.
+func (r *SQLRepository) ListAlbums() ([]*GoogleAlbum, error) {
+	var albums []*GoogleAlbum
+
+	rows, err := r.DB.Query(`SELECT data FROM albums ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var album GoogleAlbum
+		if err := json.Unmarshal([]byte(data), &album); err != nil {
+			return nil, err
+		}
+		albums = append(albums, &album)
+	}
+	return albums, rows.Err()
+}
+
+// GetAlbum fetch album by id.
+func (r *SQLRepository) GetAlbum(albumID string) (*GoogleAlbum, error) {
+	var data string
+
+	err := r.DB.QueryRow(r.rebind(`SELECT data FROM albums WHERE id = ?`), albumID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrAlbumNotExists
+	} else if err != nil {
+		return nil, err
+	}
+
+	album := new(GoogleAlbum)
+	return album, json.Unmarshal([]byte(data), album)
+}
+
+// stats count cached albums and photos.
+func (r *SQLRepository) stats() (*CacheStats, error) {
+	stats := new(CacheStats)
+	err := r.DB.QueryRow(`SELECT (SELECT COUNT(*) FROM cached_albums), (SELECT COUNT(*) FROM album_items)`).
+		Scan(&stats.Albums, &stats.Photos)
+	return stats, err
+}
+
+// clear drop all cached albums and photos.
+func (r *SQLRepository) clear() error {
+	return r.inTx(func(tx *sql.Tx) error {
+		for _, table := range []string{"cached_albums", "album_items", "media_items", "albums"} {
+			if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLRepository) upsertPhoto(tx *sql.Tx, photo *GooglePhoto) error {
+	data, err := json.Marshal(photo)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(r.rebind(`
+		INSERT INTO media_items (id, filename, mime_type, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			filename = excluded.filename, mime_type = excluded.mime_type, data = excluded.data`),
+		photo.ID, photo.Filename, photo.MimeType, string(data),
+	)
+	return err
+}
+
+// inTx run fn in a transaction committed when fn succeed.
+func (r *SQLRepository) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebind replace ? placeholders with the dialect ones.
+func (r *SQLRepository) rebind(query string) string {
+	if r.dialect != DialectPostgres {
+		return query
+	}
+
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
@@ -0,0 +1,16 @@
+package gphoto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLRepository_rebind(t *testing.T) {
+	query := `SELECT data FROM albums WHERE id = ? AND title = ?`
+	assert.Equal(t, query, (&SQLRepository{dialect: DialectSQLite}).rebind(query))
+	assert.Equal(t,
+		`SELECT data FROM albums WHERE id = $1 AND title = $2`,
+		(&SQLRepository{dialect: DialectPostgres}).rebind(query),
+	)
+}
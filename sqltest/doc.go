@@ -0,0 +1,3 @@
+// Package sqltest run gphoto.SQLRepository tests against SQLite. It is a separate module,
+// so the SQLite driver and the Go version it requires stay out of gphoto dependencies.
+package sqltest
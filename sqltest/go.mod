@@ -0,0 +1,11 @@
+module github.com/ihippik/gphoto/sqltest
+
+go 1.12
+
+require (
+	github.com/ihippik/gphoto v0.0.0
+	github.com/stretchr/testify v1.8.1
+	modernc.org/sqlite v1.20.3
+)
+
+replace github.com/ihippik/gphoto => ../
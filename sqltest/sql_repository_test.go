@@ -0,0 +1,122 @@
+package sqltest
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ihippik/gphoto"
+	"github.com/ihippik/gphoto/gphototest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// openSQLite open sqlite database in a temporary directory removed by the returned cleanup.
+func openSQLite(t *testing.T) (*sql.DB, func()) {
+	dir, err := ioutil.TempDir("", "gphoto")
+	require.NoError(t, err)
+	db, err := sql.Open("sqlite", filepath.Join(dir, "gphoto.sqlite"))
+	require.NoError(t, err)
+	// sqlite allow a single writer.
+	db.SetMaxOpenConns(1)
+	return db, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestSQLRepository_suite(t *testing.T) {
+	gphototest.RunRepositorySuite(t, func(t *testing.T) (gphoto.Repository, func()) {
+		db, cleanup := openSQLite(t)
+		repo, err := gphoto.NewSQLRepository(db, gphoto.DialectSQLite)
+		require.NoError(t, err)
+		return repo, cleanup
+	})
+}
+
+func TestSQLRepository_mediaItems(t *testing.T) {
+	db, cleanup := openSQLite(t)
+	defer cleanup()
+	repo, err := gphoto.NewSQLRepository(db, gphoto.DialectSQLite)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	photo := &gphoto.GooglePhoto{ID: "1", Filename: "IMG.jpg"}
+	require.NoError(t, repo.SavePhotos("first", []*gphoto.GooglePhoto{{ID: "2"}, {ID: "1"}}))
+	require.NoError(t, repo.SavePhotos("second", []*gphoto.GooglePhoto{photo}))
+
+	photos, err := repo.ListPhotos("first")
+	require.NoError(t, err)
+	assert.Equal(t, []*gphoto.GooglePhoto{{ID: "2"}, photo}, photos, "media items are stored once")
+
+	var items int
+	require.NoError(t, repo.DB.QueryRow(`SELECT COUNT(*) FROM media_items`).Scan(&items))
+	assert.Equal(t, 2, items)
+
+	require.NoError(t, repo.TruncateAlbum("first"))
+	require.NoError(t, repo.DB.QueryRow(`SELECT COUNT(*) FROM media_items`).Scan(&items))
+	assert.Equal(t, 1, items, "media items left without an album are dropped")
+
+	client, err := gphoto.NewGoogleClient("id", "secret", "token", gphoto.WithRepository(repo))
+	require.NoError(t, err)
+	stats, err := client.CacheStats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Albums)
+	assert.Equal(t, 1, stats.Photos)
+
+	require.NoError(t, client.ClearCache())
+	stats, err = client.CacheStats()
+	require.NoError(t, err)
+	assert.Zero(t, stats.Albums)
+}
+
+func TestSQLRepository_migrate(t *testing.T) {
+	db, cleanup := openSQLite(t)
+	defer cleanup()
+	defer db.Close()
+
+	_, err := gphoto.NewSQLRepository(db, gphoto.DialectSQLite)
+	require.NoError(t, err)
+	var versions int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&versions))
+
+	t.Run("reopen", func(t *testing.T) {
+		_, err := gphoto.NewSQLRepository(db, gphoto.DialectSQLite)
+		assert.NoError(t, err)
+		var count int
+		assert.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count))
+		assert.Equal(t, versions, count, "applied migrations are skipped")
+	})
+
+	t.Run("newer schema", func(t *testing.T) {
+		_, err := db.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, versions+1)
+		require.NoError(t, err)
+		_, err = gphoto.NewSQLRepository(db, gphoto.DialectSQLite)
+		assert.Equal(t, gphoto.ErrSchemaNewer, err)
+	})
+}
+
+func TestSQLRepository_migrateCachedAlbums(t *testing.T) {
+	db, cleanup := openSQLite(t)
+	defer cleanup()
+	defer db.Close()
+
+	for _, stmt := range []string{
+		`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY)`,
+		`INSERT INTO schema_migrations (version) VALUES (1)`,
+		`CREATE TABLE albums (id TEXT PRIMARY KEY, title TEXT NOT NULL, data TEXT NOT NULL)`,
+		`CREATE TABLE media_items (id TEXT PRIMARY KEY, filename TEXT NOT NULL, mime_type TEXT NOT NULL, data TEXT NOT NULL)`,
+		`CREATE TABLE album_items (album_id TEXT NOT NULL, position INTEGER NOT NULL, media_id TEXT NOT NULL, PRIMARY KEY (album_id, position))`,
+		`INSERT INTO media_items (id, filename, mime_type, data) VALUES ('1', '', '', '{"id":"1"}')`,
+		`INSERT INTO album_items (album_id, position, media_id) VALUES ('album', 1, '1')`,
+	} {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+
+	repo, err := gphoto.NewSQLRepository(db, gphoto.DialectSQLite)
+	require.NoError(t, err)
+	photos, err := repo.ListPhotos("album")
+	assert.NoError(t, err, "albums cached before the migration are kept")
+	assert.Equal(t, []*gphoto.GooglePhoto{{ID: "1"}}, photos)
+}
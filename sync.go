@@ -0,0 +1,505 @@
+package gphoto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+// Layouts of the local mirror, see SyncOptions.Layout.
+const (
+	LayoutByDate  = "{year}/{month}/{filename}"
+	LayoutByAlbum = "{album}/{filename}"
+)
+
+const (
+	syncBucket = "sync"
+	partSuffix = ".part"
+)
+
+var (
+	syncStoreErr = errors.New("repository does not support sync state")
+	syncErr      = errors.New("sync media error")
+	listMediaErr = errors.New("list media items error")
+	verifyErr    = errors.New("verify mirror error")
+)
+
+// syncStore keeps records about media items already fetched by Client.Sync.
+type syncStore interface {
+	syncRecords() ([]*SyncRecord, error)
+	saveSyncRecord(record *SyncRecord) error
+}
+
+// SyncOptions describe what to mirror and where.
+type SyncOptions struct {
+	// Dir is a root directory of the local mirror.
+	Dir string
+	// Layout is a path template relative to Dir, LayoutByDate by default.
+	// Supported placeholders: {album}, {year}, {month}, {day}, {filename}, {id}.
+	Layout string
+	// Albums limits sync to the given album IDs. When empty the whole library
+	// is mirrored, or every album if Layout refers to {album}.
+	Albums []string
+	// DryRun only plans the sync: nothing is downloaded or recorded.
+	DryRun bool
+}
+
+// SyncRecord represent media item fetched into the local mirror.
+type SyncRecord struct {
+	MediaID   string    `json:"mediaId"`
+	AlbumID   string    `json:"albumId,omitempty"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256,omitempty"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// SyncReport summarize a sync run.
+type SyncReport struct {
+	DryRun  bool
+	Fetched []*SyncRecord
+	Skipped int
+	Bytes   int64
+}
+
+// Print write a human readable report.
+func (r *SyncReport) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	action := "fetched"
+	if r.DryRun {
+		action = "planned"
+	}
+	for _, item := range r.Fetched {
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%d\n", item.MediaID, item.Path, item.Size); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(tw, "%s: %d, skipped: %d, bytes: %d\n", action, len(r.Fetched), r.Skipped, r.Bytes); err != nil {
+		return err
+	}
+	return tw.Flush()
+}
+
+// syncItem is a media item to mirror with the album it was found in.
+type syncItem struct {
+	photo *GooglePhoto
+	album *GoogleAlbum
+}
+
+// Sync mirror new media items into a local directory tree.
+// Fetched items are recorded in the repository so reruns only fetch new ones.
+func (c *Client) Sync(opts SyncOptions) (report *SyncReport, err error) {
+	c, _, end := c.startSpan("gphoto.Sync")
+	defer func() {
+		if report != nil {
+			end(err, Attr(AttrItemCount, len(report.Fetched)))
+		} else {
+			end(err)
+		}
+	}()
+
+	store, ok := c.repo.(syncStore)
+	if !ok {
+		return nil, syncStoreErr
+	}
+	if opts.Layout == "" {
+		opts.Layout = LayoutByDate
+	}
+
+	records, err := store.syncRecords()
+	if err != nil {
+		logrus.WithError(err).Errorln(syncErr)
+		return nil, syncErr
+	}
+	fetched := make(map[string]bool, len(records))
+	claimed := make(map[string]bool, len(records))
+	for _, record := range records {
+		fetched[syncKey(record.AlbumID, record.MediaID)] = true
+		claimed[record.Path] = true
+	}
+
+	items, err := c.syncItems(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report = &SyncReport{DryRun: opts.DryRun}
+	byAlbum := strings.Contains(opts.Layout, "{album}")
+	for _, item := range items {
+		record := &SyncRecord{MediaID: item.photo.ID}
+		if byAlbum && item.album != nil {
+			record.AlbumID = item.album.ID
+		}
+		if fetched[syncKey(record.AlbumID, record.MediaID)] {
+			report.Skipped++
+			continue
+		}
+		record.Path = claimPath(opts.Dir, layoutPath(opts.Layout, item), claimed)
+
+		if !opts.DryRun {
+			record.Size, record.SHA256, err = c.fetchMedia(item.photo, filepath.Join(opts.Dir, record.Path))
+			if err != nil {
+				logrus.WithError(err).WithField("media", record.MediaID).Errorln(syncErr)
+				return report, syncErr
+			}
+			record.FetchedAt = time.Now()
+			if err = store.saveSyncRecord(record); err != nil {
+				logrus.WithError(err).WithField("media", record.MediaID).Errorln(syncErr)
+				return report, syncErr
+			}
+		}
+		fetched[syncKey(record.AlbumID, record.MediaID)] = true
+		report.Fetched = append(report.Fetched, record)
+		report.Bytes += record.Size
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"fetched": len(report.Fetched),
+		"skipped": report.Skipped,
+		"dryRun":  opts.DryRun,
+	}).Infoln("sync finished")
+	return report, nil
+}
+
+// syncItems collect media items to mirror according to options.
+func (c *Client) syncItems(opts SyncOptions) ([]syncItem, error) {
+	var items []syncItem
+
+	if len(opts.Albums) == 0 && !strings.Contains(opts.Layout, "{album}") {
+		var photos []*GooglePhoto
+		err := c.withAuth(func(accessToken string) error {
+			var err error
+			photos, err = c.api.listMediaItems(accessToken)
+			return err
+		})
+		if err == refreshTokenErr || err == ErrQuotaExceeded {
+			return nil, err
+		} else if err != nil {
+			logrus.WithError(err).Errorln(listMediaErr)
+			return nil, listMediaErr
+		}
+		for _, photo := range photos {
+			items = append(items, syncItem{photo: photo})
+		}
+		return items, nil
+	}
+
+	albums, err := c.GetAlbumList()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(opts.Albums))
+	for _, id := range opts.Albums {
+		wanted[id] = true
+	}
+	for _, album := range albums {
+		if len(wanted) > 0 && !wanted[album.ID] {
+			continue
+		}
+		photos, err := c.GetPhotoByAlbum(album.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, photo := range photos {
+			items = append(items, syncItem{photo: photo, album: album})
+		}
+	}
+	return items, nil
+}
+
+// fetchMedia download original media content into the file and return its size and SHA-256.
+// Content is written to a temporary file first, an interrupted download is resumed
+// with a Range request when the server supports it.
+func (c *Client) fetchMedia(photo *GooglePhoto, path string) (int64, string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, "", err
+	}
+
+	part := path + partSuffix
+	file, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// hash already downloaded part, it leaves the file offset at the end.
+	hash := sha256.New()
+	offset, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+
+	body, resumed, err := c.api.download(downloadURL(photo), offset)
+	if err == rangeErr {
+		body, resumed, err = c.api.download(downloadURL(photo), 0)
+	}
+	if err != nil {
+		return offset, "", err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+
+	if !resumed && offset > 0 {
+		if err = restartFile(file); err != nil {
+			return 0, "", err
+		}
+		hash.Reset()
+		offset = 0
+	}
+	logrus.WithFields(logrus.Fields{"media": photo.ID, "offset": offset}).Debugln("media download started")
+
+	n, err := io.Copy(io.MultiWriter(file, hash), body)
+	c.observeDownload(n)
+	if err != nil {
+		return offset + n, "", err
+	}
+	if err = file.Close(); err != nil {
+		return offset + n, "", err
+	}
+	if err = os.Rename(part, path); err != nil {
+		return offset + n, "", err
+	}
+
+	size := offset + n
+	logrus.WithFields(logrus.Fields{"media": photo.ID, "size": size}).Debugln("media fetched")
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// restartFile truncate the file and rewind it to the beginning.
+func restartFile(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := file.Seek(0, io.SeekStart)
+	return err
+}
+
+// VerifyReport summarize a verify pass over the local mirror.
+type VerifyReport struct {
+	Checked   int
+	Missing   []*SyncRecord
+	Corrupt   []*SyncRecord
+	Refetched []*SyncRecord
+}
+
+// VerifySync check local copies of fetched media items against recorded size and SHA-256.
+// Missing or corrupt copies are fetched again when refetch is set.
+func (c *Client) VerifySync(dir string, refetch bool) (*VerifyReport, error) {
+	store, ok := c.repo.(syncStore)
+	if !ok {
+		return nil, syncStoreErr
+	}
+
+	records, err := store.syncRecords()
+	if err != nil {
+		logrus.WithError(err).Errorln(verifyErr)
+		return nil, verifyErr
+	}
+
+	report := new(VerifyReport)
+	for _, record := range records {
+		report.Checked++
+		path := filepath.Join(dir, record.Path)
+
+		size, sum, err := fileChecksum(path)
+		switch {
+		case os.IsNotExist(err):
+			report.Missing = append(report.Missing, record)
+		case err != nil:
+			logrus.WithError(err).WithField("path", path).Errorln(verifyErr)
+			return report, verifyErr
+		case size != record.Size || (record.SHA256 != "" && sum != record.SHA256):
+			report.Corrupt = append(report.Corrupt, record)
+		default:
+			continue
+		}
+
+		if !refetch {
+			continue
+		}
+		if err = c.refetchMedia(record, path); err != nil {
+			logrus.WithError(err).WithField("media", record.MediaID).Errorln(verifyErr)
+			return report, verifyErr
+		}
+		if err = store.saveSyncRecord(record); err != nil {
+			logrus.WithError(err).WithField("media", record.MediaID).Errorln(verifyErr)
+			return report, verifyErr
+		}
+		report.Refetched = append(report.Refetched, record)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"checked":   report.Checked,
+		"missing":   len(report.Missing),
+		"corrupt":   len(report.Corrupt),
+		"refetched": len(report.Refetched),
+	}).Infoln("verify finished")
+	return report, nil
+}
+
+// refetchMedia download the recorded media item again with a fresh base url.
+func (c *Client) refetchMedia(record *SyncRecord, path string) error {
+	var photo *GooglePhoto
+
+	err := c.withAuth(func(accessToken string) error {
+		var err error
+		photo, err = c.api.getMediaItem(accessToken, record.MediaID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = os.Remove(path + partSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if record.Size, record.SHA256, err = c.fetchMedia(photo, path); err != nil {
+		return err
+	}
+	record.FetchedAt = time.Now()
+	return nil
+}
+
+// fileChecksum calculate size and SHA-256 of the file.
+func fileChecksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return size, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// downloadURL build url of the original media content.
+func downloadURL(photo *GooglePhoto) string {
+	if photo.IsVideo() {
+		return photo.BaseURL + "=dv"
+	}
+	return photo.BaseURL + "=d"
+}
+
+// layoutPath fill layout placeholders with media item values.
+func layoutPath(layout string, item syncItem) string {
+	created := item.photo.MediaMetadata.CreationTime
+	var album string
+	if item.album != nil {
+		album = item.album.Title
+	}
+	filename := item.photo.Filename
+	if filename == "" {
+		filename = item.photo.ID
+	}
+
+	r := strings.NewReplacer(
+		"{album}", sanitizeName(album),
+		"{year}", created.Format("2006"),
+		"{month}", created.Format("01"),
+		"{day}", created.Format("02"),
+		"{filename}", sanitizeName(filename),
+		"{id}", sanitizeName(item.photo.ID),
+	)
+	return filepath.Clean(filepath.FromSlash(r.Replace(layout)))
+}
+
+// claimPath reserve a unique path, a numeric suffix is added on collision.
+func claimPath(dir, path string, claimed map[string]bool) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	candidate := path
+	for i := 1; ; i++ {
+		if !claimed[candidate] {
+			if _, err := os.Stat(filepath.Join(dir, candidate)); os.IsNotExist(err) {
+				break
+			}
+		}
+		candidate = base + "_" + strconv.Itoa(i) + ext
+	}
+	claimed[candidate] = true
+	return candidate
+}
+
+// sanitizeName make a single path element from the name.
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+func syncKey(albumID, mediaID string) string {
+	return albumID + "/" + mediaID
+}
+
+// syncRecords fetch all records of the sync bucket.
+func (r *BoltRepository) syncRecords() ([]*SyncRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var records []*SyncRecord
+
+	err := r.view(func(tx *bbolt.Tx) error {
+		bucket := r.buckets(tx).Bucket([]byte(syncBucket))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var record SyncRecord
+			if err := r.unmarshal(v, &record); err != nil {
+				return err
+			}
+			records = append(records, &record)
+			return nil
+		})
+	})
+	return records, err
+}
+
+// saveSyncRecord save record of fetched media item into sync bucket.
+func (r *BoltRepository) saveSyncRecord(record *SyncRecord) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.update(func(tx *bbolt.Tx) error {
+		bucket, err := r.buckets(tx).CreateBucketIfNotExists([]byte(syncBucket))
+		if err != nil {
+			return err
+		}
+		buf, err := r.marshal(record)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(syncKey(record.AlbumID, record.MediaID)), buf)
+	})
+}
@@ -0,0 +1,172 @@
+package gphoto
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Sync(t *testing.T) {
+	created := time.Date(2019, 7, 14, 10, 0, 0, 0, time.UTC)
+	library := []*GooglePhoto{
+		{ID: "first", BaseURL: "http://photo/first", MimeType: "image/jpeg", Filename: "IMG.jpg"},
+		{ID: "second", BaseURL: "http://photo/second", MimeType: "video/mp4", Filename: "IMG.jpg"},
+	}
+	for _, photo := range library {
+		photo.MediaMetadata.CreationTime = created
+	}
+
+	api := new(MockedApi)
+	c, dir := newBoltClient(t, api)
+	defer os.RemoveAll(dir)
+	defer c.Close()
+	mirror := filepath.Join(dir, "mirror")
+
+	api.On("listMediaItems", "ACCESS_TOKEN").Return(library, nil).Times(3)
+	api.On("download", "http://photo/first=d", int64(0)).Return(ioutil.NopCloser(strings.NewReader("first")), false, nil).Once()
+	api.On("download", "http://photo/second=dv", int64(0)).Return(ioutil.NopCloser(strings.NewReader("second")), false, nil).Once()
+
+	t.Run("dry run", func(t *testing.T) {
+		report, err := c.Sync(SyncOptions{Dir: mirror, DryRun: true})
+		assert.NoError(t, err)
+		assert.Len(t, report.Fetched, 2)
+		_, err = os.Stat(mirror)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("fetch new items", func(t *testing.T) {
+		report, err := c.Sync(SyncOptions{Dir: mirror})
+		assert.NoError(t, err)
+		if assert.Len(t, report.Fetched, 2) {
+			assert.Equal(t, filepath.Join("2019", "07", "IMG.jpg"), report.Fetched[0].Path)
+			assert.Equal(t, filepath.Join("2019", "07", "IMG_1.jpg"), report.Fetched[1].Path)
+		}
+		assert.Equal(t, int64(len("first")+len("second")), report.Bytes)
+		assert.Equal(t, "a7937b64b8caa58f03721bb6bacf5c78cb235febe0e70b1b84cd99541461a08e", report.Fetched[0].SHA256)
+
+		data, err := ioutil.ReadFile(filepath.Join(mirror, "2019", "07", "IMG_1.jpg"))
+		assert.NoError(t, err)
+		assert.Equal(t, "second", string(data))
+	})
+
+	t.Run("rerun skip fetched", func(t *testing.T) {
+		report, err := c.Sync(SyncOptions{Dir: mirror})
+		assert.NoError(t, err)
+		assert.Empty(t, report.Fetched)
+		assert.Equal(t, 2, report.Skipped)
+	})
+
+	api.AssertExpectations(t)
+}
+
+func TestClient_fetchMedia(t *testing.T) {
+	api := new(MockedApi)
+	c, dir := newBoltClient(t, api)
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	photo := &GooglePhoto{ID: "first", BaseURL: "http://photo/first"}
+	path := filepath.Join(dir, "first.jpg")
+
+	t.Run("resume partial download", func(t *testing.T) {
+		assert.NoError(t, ioutil.WriteFile(path+partSuffix, []byte("fir"), 0644))
+		api.On("download", "http://photo/first=d", int64(3)).Return(ioutil.NopCloser(strings.NewReader("st")), true, nil).Once()
+
+		size, sum, err := c.fetchMedia(photo, path)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), size)
+		assert.Equal(t, "a7937b64b8caa58f03721bb6bacf5c78cb235febe0e70b1b84cd99541461a08e", sum)
+		_, err = os.Stat(path + partSuffix)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("range is not supported", func(t *testing.T) {
+		assert.NoError(t, ioutil.WriteFile(path+partSuffix, []byte("xyz"), 0644))
+		api.On("download", "http://photo/first=d", int64(3)).Return(ioutil.NopCloser(strings.NewReader("first")), false, nil).Once()
+
+		size, _, err := c.fetchMedia(photo, path)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), size)
+		data, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "first", string(data))
+	})
+
+	t.Run("interrupted download keep part", func(t *testing.T) {
+		api.On("download", "http://photo/first=d", int64(0)).Return(nil, false, someErr).Once()
+
+		_, _, err := c.fetchMedia(&GooglePhoto{ID: "first", BaseURL: "http://photo/first"}, path+".new")
+		assert.Equal(t, someErr, err)
+		_, err = os.Stat(path + ".new" + partSuffix)
+		assert.NoError(t, err)
+	})
+
+	api.AssertExpectations(t)
+}
+
+func TestClient_VerifySync(t *testing.T) {
+	api := new(MockedApi)
+	c, dir := newBoltClient(t, api)
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	store := c.repo.(syncStore)
+	records := []*SyncRecord{
+		{MediaID: "ok", Path: "ok.jpg", Size: 5, SHA256: "a7937b64b8caa58f03721bb6bacf5c78cb235febe0e70b1b84cd99541461a08e"},
+		{MediaID: "corrupt", Path: "corrupt.jpg", Size: 5, SHA256: "a7937b64b8caa58f03721bb6bacf5c78cb235febe0e70b1b84cd99541461a08e"},
+		{MediaID: "missing", Path: "missing.jpg", Size: 5},
+	}
+	for _, record := range records {
+		assert.NoError(t, store.saveSyncRecord(record))
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ok.jpg"), []byte("first"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "corrupt.jpg"), []byte("fxrst"), 0644))
+
+	t.Run("detect", func(t *testing.T) {
+		report, err := c.VerifySync(dir, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, report.Checked)
+		assert.Len(t, report.Corrupt, 1)
+		assert.Len(t, report.Missing, 1)
+		assert.Empty(t, report.Refetched)
+	})
+
+	t.Run("refetch", func(t *testing.T) {
+		for _, id := range []string{"corrupt", "missing"} {
+			api.On("getMediaItem", "ACCESS_TOKEN", id).Return(&GooglePhoto{ID: id, BaseURL: "http://photo/" + id}, nil).Once()
+			api.On("download", "http://photo/"+id+"=d", int64(0)).Return(ioutil.NopCloser(strings.NewReader("first")), false, nil).Once()
+		}
+
+		report, err := c.VerifySync(dir, true)
+		assert.NoError(t, err)
+		assert.Len(t, report.Refetched, 2)
+
+		report, err = c.VerifySync(dir, false)
+		assert.NoError(t, err)
+		assert.Empty(t, report.Corrupt)
+		assert.Empty(t, report.Missing)
+	})
+
+	api.AssertExpectations(t)
+}
+
+func TestClient_SyncUnsupportedRepo(t *testing.T) {
+	c := &Client{api: new(MockedApi), repo: new(MockedRepo)}
+	_, err := c.Sync(SyncOptions{Dir: "mirror"})
+	assert.Equal(t, syncStoreErr, err)
+}
+
+func Test_layoutPath(t *testing.T) {
+	photo := &GooglePhoto{ID: "id", Filename: "a/b.jpg"}
+	photo.MediaMetadata.CreationTime = time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	item := syncItem{photo: photo, album: &GoogleAlbum{ID: "album", Title: "Trip: Rome"}}
+
+	assert.Equal(t, filepath.Join("2020", "01", "a_b.jpg"), layoutPath(LayoutByDate, item))
+	assert.Equal(t, filepath.Join("Trip_ Rome", "a_b.jpg"), layoutPath(LayoutByAlbum, item))
+	assert.Equal(t, filepath.Join("02", "id"), layoutPath("{day}/{id}", item))
+}
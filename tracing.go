@@ -0,0 +1,156 @@
+package gphoto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Attribute keys set on spans.
+const (
+	AttrAlbumID     = "gphoto.album_id"
+	AttrMediaID     = "gphoto.media_id"
+	AttrItemCount   = "gphoto.item_count"
+	AttrCacheResult = "gphoto.cache_result"
+	AttrRepository  = "gphoto.repository"
+	AttrEndpoint    = "gphoto.endpoint"
+	AttrAttempt     = "gphoto.attempt"
+	AttrHTTPMethod  = "http.method"
+	AttrHTTPStatus  = "http.status_code"
+)
+
+// Tracer start spans, its shape follows OpenTelemetry so an adapter is a few lines.
+type Tracer interface {
+	// Start span as a child of the span in ctx and return ctx holding the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError mark the span as failed.
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key-value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr make Attribute.
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) RecordError(error)          {}
+func (nopSpan) End()                       {}
+
+// WithContext return Client whose spans are children of the span in ctx.
+// The returned Client share the cache and tokens with c and is meant for a single caller,
+// e.g. an incoming HTTP request.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	// the state guarded by mu is read from the origin, so it is not copied.
+	copied := &Client{
+		clientID:     c.clientID,
+		clientSecret: c.clientSecret,
+		api:          c.api,
+		repo:         c.repo,
+		photoTTL:     c.photoTTL,
+		albumTTL:     c.albumTTL,
+		limits:       c.limits,
+		metrics:      c.metrics,
+		tracer:       c.tracer,
+		ctx:          ctx,
+		base:         c.origin(),
+		limiter:      c.limiter,
+	}
+	if g, ok := c.api.(*googleApi); ok {
+		api := *g
+		api.ctx = ctx
+		copied.api = &api
+	}
+	return copied
+}
+
+// origin return the client WithContext copies were made from, it holds the shared state.
+func (c *Client) origin() *Client {
+	if c.base != nil {
+		return c.base
+	}
+	return c
+}
+
+// startSpan start span of the Client operation and return a copy of c bound to it,
+// api requests and nested operations of the copy are traced as children of the span.
+// c is returned as is without a tracer. end set attrs and record err if it is not nil.
+func (c *Client) startSpan(name string, attrs ...Attribute) (traced *Client, span Span, end func(err error, attrs ...Attribute)) {
+	if c.tracer == nil {
+		return c, nopSpan{}, func(error, ...Attribute) {}
+	}
+
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span = c.tracer.Start(ctx, name)
+	span.SetAttributes(attrs...)
+
+	return c.WithContext(ctx), span, func(err error, attrs ...Attribute) {
+		span.SetAttributes(attrs...)
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+}
+
+// traceRepo run repository operation in a span.
+func (c *Client) traceRepo(name, albumID string, op func() error) error {
+	_, span, end := c.startSpan("gphoto.repository."+name, Attr(AttrRepository, fmt.Sprintf("%T", c.repo)))
+	if albumID != "" {
+		span.SetAttributes(Attr(AttrAlbumID, albumID))
+	}
+
+	err := op()
+	if err == ErrAlbumNotExists {
+		end(nil)
+	} else {
+		end(err)
+	}
+	return err
+}
+
+// startRequestSpan start span of the request attempt and bind the request to its context.
+func (g *googleApi) startRequestSpan(req *http.Request, attempt int) (*http.Request, Span) {
+	if g.tracer == nil {
+		return req, nopSpan{}
+	}
+
+	endpoint := g.endpoint(req)
+	ctx, span := g.tracer.Start(req.Context(), "HTTP "+req.Method+" "+endpoint)
+	span.SetAttributes(
+		Attr(AttrHTTPMethod, req.Method),
+		Attr(AttrEndpoint, endpoint),
+		Attr(AttrAttempt, attempt),
+	)
+	return req.WithContext(ctx), span
+}
+
+// endRequestSpan record the response of the attempt and end its span.
+func endRequestSpan(span Span, res *http.Response, err error) {
+	if err != nil {
+		span.RecordError(err)
+	} else {
+		span.SetAttributes(Attr(AttrHTTPStatus, res.StatusCode))
+		if res.StatusCode >= http.StatusBadRequest {
+			span.RecordError(errors.New(res.Status))
+		}
+	}
+	span.End()
+}
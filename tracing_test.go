@@ -0,0 +1,217 @@
+package gphoto
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+// recordedSpan is a span kept by recordingTracer.
+type recordedSpan struct {
+	name   string
+	parent *recordedSpan
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *recordedSpan) End() {
+	s.ended = true
+}
+
+// recordingTracer keep started spans in order.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attrs: make(map[string]interface{})}
+	r.spans = append(r.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// children return names of spans started under the parent.
+func (r *recordingTracer) children(parent *recordedSpan) []string {
+	var names []string
+	for _, span := range r.spans {
+		if span.parent == parent {
+			names = append(names, span.name)
+		}
+	}
+	return names
+}
+
+func (r *recordingTracer) find(name string) *recordedSpan {
+	for _, span := range r.spans {
+		if span.name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestClient_tracing(t *testing.T) {
+	var (
+		server  *httptest.Server
+		refresh int
+	)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			refresh++
+			_, _ = w.Write([]byte(`{"access_token":"token"}`))
+		case r.Header.Get("Authorization") != "Bearer token" && strings.HasPrefix(r.URL.Path, "/v1/"):
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v1/mediaItems:search":
+			_, _ = w.Write([]byte(`{"mediaItems":[{"id":"a"},{"id":"b"}]}`))
+		case r.URL.Path == "/v1/albums":
+			_, _ = w.Write([]byte(`{"albums":[{"id":"album"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	tracer := new(recordingTracer)
+	client, err := NewGoogleClient("id", "secret", "refresh",
+		WithEndpoints(server.URL, server.URL+"/token"),
+		WithRepository(NewMemoryRepository(0, 0, 0)),
+		WithTracer(tracer),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, root := tracer.Start(context.Background(), "render page")
+	photos, err := client.WithContext(ctx).GetPhotoByAlbum("album")
+	require.NoError(t, err)
+	assert.Len(t, photos, 2)
+
+	op := tracer.find("gphoto.GetPhotoByAlbum")
+	require.NotNil(t, op)
+	assert.Equal(t, root, op.parent)
+	assert.Equal(t, []string{
+		"gphoto.repository.ListPhotos",
+		"HTTP POST mediaItems:search",
+		"gphoto.refreshToken",
+		"HTTP POST mediaItems:search",
+		"gphoto.repository.TruncateAlbum",
+		"gphoto.repository.SavePhotos",
+	}, tracer.children(op))
+	assert.Equal(t, []string{"HTTP POST token"}, tracer.children(tracer.find("gphoto.refreshToken")))
+	assert.Equal(t, "album", op.attrs[AttrAlbumID])
+	assert.Equal(t, 2, op.attrs[AttrItemCount])
+	assert.Equal(t, CacheMiss, op.attrs[AttrCacheResult])
+	assert.NoError(t, op.err)
+
+	unauthorized := tracer.find("HTTP POST mediaItems:search")
+	assert.Equal(t, http.StatusUnauthorized, unauthorized.attrs[AttrHTTPStatus])
+	assert.Error(t, unauthorized.err)
+	assert.Nil(t, tracer.find("gphoto.repository.ListPhotos").err, "missing album is not an error")
+
+	for _, span := range tracer.spans {
+		if span != root {
+			assert.True(t, span.ended, span.name)
+		}
+	}
+
+	_, err = client.GetAlbumList()
+	require.NoError(t, err)
+	assert.Equal(t, 1, refresh, "access token is shared with WithContext copies")
+	assert.Nil(t, tracer.find("gphoto.GetAlbumList").parent)
+}
+
+func TestClient_WithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client, err := NewGoogleClient("id", "secret", "refresh",
+		WithEndpoints(server.URL, server.URL+"/token"),
+		WithRepository(NewMemoryRepository(0, 0, 0)),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.WithContext(ctx).GetAlbumList()
+	assert.Equal(t, getAlbumErr, err, "canceled context abort requests")
+}
+
+func TestClient_tracingConcurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			_, _ = w.Write([]byte(`{"access_token":"token"}`))
+		case "/v1/mediaItems:search":
+			_, _ = w.Write([]byte(`{"mediaItems":[{"id":"a"}]}`))
+		case "/v1/albums":
+			_, _ = w.Write([]byte(`{"albums":[{"id":"album"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	tracer := new(recordingTracer)
+	client, err := NewGoogleClient("id", "secret", "refresh",
+		WithEndpoints(server.URL, server.URL+"/token"),
+		WithRepository(NewMemoryRepository(0, 0, 0)),
+		WithTracer(tracer),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	const callers = 8
+	roots := make(map[*recordedSpan]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		ctx, root := tracer.Start(context.Background(), "request")
+		roots[root.(*recordedSpan)] = true
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := client.WithContext(ctx).GetPhotoByAlbum("album")
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := client.GetAlbumList()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	for _, span := range tracer.spans {
+		switch {
+		case span.name == "gphoto.GetPhotoByAlbum":
+			assert.True(t, roots[span.parent], "operation is a child of its caller span")
+		case span.name == "gphoto.GetAlbumList":
+			assert.Nil(t, span.parent, "operation of the origin client is a root span")
+		case strings.HasPrefix(span.name, "HTTP ") && span.parent.name != "gphoto.refreshToken":
+			assert.Contains(t, []string{"gphoto.GetPhotoByAlbum", "gphoto.GetAlbumList"}, span.parent.name)
+		}
+	}
+}
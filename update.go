@@ -0,0 +1,99 @@
+package gphoto
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	updateMediaErr     = errors.New("update media item error")
+	updateAlbumErr     = errors.New("update album error")
+	invalidResponseErr = errors.New("api response does not match the update")
+)
+
+// UpdateMediaItemDescription change description of the media item created by the app.
+// Cached records of the item are updated in place.
+func (c *Client) UpdateMediaItemDescription(mediaID, description string) (*GooglePhoto, error) {
+	var photo *GooglePhoto
+
+	err := c.withAuth(func(accessToken string) error {
+		var err error
+		photo, err = c.api.patchMediaItem(accessToken, mediaID, description)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return nil, err
+	} else if err != nil {
+		logrus.WithError(err).WithField("media", mediaID).Errorln(updateMediaErr)
+		return nil, updateMediaErr
+	}
+	if photo.ID != mediaID || photo.Description != description {
+		logrus.WithFields(logrus.Fields{"media": mediaID, "got": photo.ID}).Errorln(invalidResponseErr)
+		return nil, invalidResponseErr
+	}
+
+	if err = c.repo.UpdatePhoto(photo); err != nil {
+		logrus.WithError(err).WithField("media", mediaID).Errorln(saveErr)
+		return photo, saveErr
+	}
+	return photo, nil
+}
+
+// UpdateAlbumTitle change title of the album created by the app.
+// Cached record of the album and the album list kept in memory are updated in place.
+func (c *Client) UpdateAlbumTitle(albumID, title string) (*GoogleAlbum, error) {
+	var album *GoogleAlbum
+
+	err := c.withAuth(func(accessToken string) error {
+		var err error
+		album, err = c.api.patchAlbum(accessToken, albumID, title)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return nil, err
+	} else if err != nil {
+		logrus.WithError(err).WithField("album", albumID).Errorln(updateAlbumErr)
+		return nil, updateAlbumErr
+	}
+	if album.ID != albumID || album.Title != title {
+		logrus.WithFields(logrus.Fields{"album": albumID, "got": album.ID}).Errorln(invalidResponseErr)
+		return nil, invalidResponseErr
+	}
+
+	cached, err := c.repo.GetAlbum(albumID)
+	if err == ErrAlbumNotExists {
+		cached = album
+	} else if err != nil {
+		logrus.WithError(err).WithField("album", albumID).Errorln(saveErr)
+		return album, saveErr
+	}
+	cached.Title = album.Title
+	if err = c.repo.SaveAlbums([]*GoogleAlbum{cached}); err != nil {
+		logrus.WithError(err).WithField("album", albumID).Errorln(saveErr)
+		return album, saveErr
+	}
+	c.retitleMemo(albumID, album.Title)
+	return album, nil
+}
+
+// retitleMemo change title of the album in the album list kept in memory,
+// the list is copied as callers may still hold the previous one.
+func (c *Client) retitleMemo(albumID, title string) {
+	memo := c.origin()
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+
+	for i, album := range memo.albums {
+		if album.ID != albumID {
+			continue
+		}
+		albums := make([]*GoogleAlbum, len(memo.albums))
+		copy(albums, memo.albums)
+		retitled := *album
+		retitled.Title = title
+		albums[i] = &retitled
+		memo.albums = albums
+		return
+	}
+}
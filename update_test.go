@@ -0,0 +1,110 @@
+package gphoto
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_UpdateMediaItemDescription(t *testing.T) {
+	api := new(MockedApi)
+	c, dir := newBoltClient(t, api)
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	photos := []*GooglePhoto{{ID: "first"}, {ID: "second"}}
+	assert.NoError(t, c.repo.SavePhotos("album", photos))
+	assert.NoError(t, c.repo.SavePhotos("other", photos[1:]))
+
+	t.Run("success", func(t *testing.T) {
+		updated := &GooglePhoto{ID: "second", Description: "new"}
+		api.On("patchMediaItem", "ACCESS_TOKEN", "second", "new").Return(updated, nil).Once()
+
+		got, err := c.UpdateMediaItemDescription("second", "new")
+		assert.NoError(t, err)
+		assert.Equal(t, updated, got)
+
+		cached, err := c.repo.ListPhotos("album")
+		assert.NoError(t, err)
+		assert.Equal(t, []*GooglePhoto{{ID: "first"}, updated}, cached)
+		cached, err = c.repo.ListPhotos("other")
+		assert.NoError(t, err)
+		assert.Equal(t, []*GooglePhoto{updated}, cached)
+	})
+
+	t.Run("response mismatch", func(t *testing.T) {
+		api.On("patchMediaItem", "ACCESS_TOKEN", "first", "new").Return(&GooglePhoto{ID: "first"}, nil).Once()
+
+		_, err := c.UpdateMediaItemDescription("first", "new")
+		assert.Equal(t, invalidResponseErr, err)
+	})
+
+	t.Run("api fail", func(t *testing.T) {
+		api.On("patchMediaItem", "ACCESS_TOKEN", "first", "new").Return(nil, someErr).Once()
+
+		_, err := c.UpdateMediaItemDescription("first", "new")
+		assert.Equal(t, updateMediaErr, err)
+	})
+
+	api.AssertExpectations(t)
+}
+
+func TestClient_UpdateAlbumTitle(t *testing.T) {
+	api := new(MockedApi)
+	c, dir := newBoltClient(t, api)
+	defer os.RemoveAll(dir)
+	defer c.Close()
+
+	shareInfo := &ShareInfo{ShareToken: "token"}
+	assert.NoError(t, c.repo.SaveAlbums([]*GoogleAlbum{{ID: "album", Title: "old", ShareInfo: shareInfo}}))
+
+	t.Run("cached album", func(t *testing.T) {
+		api.On("patchAlbum", "ACCESS_TOKEN", "album", "new").Return(&GoogleAlbum{ID: "album", Title: "new"}, nil).Once()
+
+		got, err := c.UpdateAlbumTitle("album", "new")
+		assert.NoError(t, err)
+		assert.Equal(t, "new", got.Title)
+
+		cached, err := c.repo.GetAlbum("album")
+		assert.NoError(t, err)
+		assert.Equal(t, &GoogleAlbum{ID: "album", Title: "new", ShareInfo: shareInfo}, cached)
+	})
+
+	t.Run("not cached album", func(t *testing.T) {
+		api.On("patchAlbum", "ACCESS_TOKEN", "unknown", "new").Return(&GoogleAlbum{ID: "unknown", Title: "new"}, nil).Once()
+
+		_, err := c.UpdateAlbumTitle("unknown", "new")
+		assert.NoError(t, err)
+		cached, err := c.repo.GetAlbum("unknown")
+		assert.NoError(t, err)
+		assert.Equal(t, "new", cached.Title)
+	})
+
+	t.Run("album list in memory", func(t *testing.T) {
+		c.albumTTL = time.Hour
+		defer func() { c.albumTTL = 0 }()
+		api.On("getAlbumList", "ACCESS_TOKEN").Return([]*GoogleAlbum{{ID: "album", Title: "new"}}, nil).Once()
+		api.On("patchAlbum", "ACCESS_TOKEN", "album", "memo").Return(&GoogleAlbum{ID: "album", Title: "memo"}, nil).Once()
+
+		before, err := c.GetAlbumList()
+		assert.NoError(t, err)
+		_, err = c.UpdateAlbumTitle("album", "memo")
+		assert.NoError(t, err)
+
+		albums, err := c.GetAlbumList()
+		assert.NoError(t, err)
+		assert.Equal(t, "memo", albums[0].Title)
+		assert.Equal(t, "new", before[0].Title, "previously returned list is not changed")
+	})
+
+	t.Run("response mismatch", func(t *testing.T) {
+		api.On("patchAlbum", "ACCESS_TOKEN", "album", "other").Return(&GoogleAlbum{ID: "album", Title: "new"}, nil).Once()
+
+		_, err := c.UpdateAlbumTitle("album", "other")
+		assert.Equal(t, invalidResponseErr, err)
+	})
+
+	api.AssertExpectations(t)
+}
@@ -0,0 +1,68 @@
+package gphoto
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+var uploadErr = errors.New("upload media error")
+
+// Upload create a new media item from the content, album is optional.
+// Content is rewound before every upload attempt.
+func (c *Client) Upload(albumID, fileName, description string, content io.ReadSeeker) (photo *GooglePhoto, err error) {
+	c, span, end := c.startSpan("gphoto.Upload", Attr(AttrAlbumID, albumID))
+	defer func() {
+		if photo != nil {
+			span.SetAttributes(Attr(AttrMediaID, photo.ID))
+		}
+		end(err)
+	}()
+
+	var uploadToken string
+	err = c.withAuth(func(accessToken string) error {
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		var err error
+		uploadToken, err = c.api.uploadMedia(accessToken, fileName, content)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return nil, err
+	} else if err != nil {
+		logrus.WithError(err).WithField("file", fileName).Errorln(uploadErr)
+		return nil, uploadErr
+	}
+
+	err = c.withAuth(func(accessToken string) error {
+		var err error
+		photo, err = c.api.createMediaItem(accessToken, albumID, uploadToken, fileName, description)
+		return err
+	})
+	if err == refreshTokenErr || err == ErrQuotaExceeded {
+		return nil, err
+	} else if err != nil {
+		logrus.WithError(err).WithField("file", fileName).Errorln(uploadErr)
+		return nil, uploadErr
+	}
+
+	logrus.WithFields(logrus.Fields{"file": fileName, "media": photo.ID}).Infoln("media uploaded")
+	return photo, nil
+}
+
+// UploadFile create a new media item from the local file, album is optional.
+func (c *Client) UploadFile(albumID, path, description string) (*GooglePhoto, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return c.Upload(albumID, filepath.Base(path), description, file)
+}
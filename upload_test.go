@@ -0,0 +1,59 @@
+package gphoto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClient_Upload(t *testing.T) {
+	photo := &GooglePhoto{ID: "uploaded", Filename: "photo.jpg"}
+
+	tests := []struct {
+		name    string
+		setup   func(api *MockedApi)
+		want    *GooglePhoto
+		wantErr error
+	}{
+		{
+			name: "success",
+			setup: func(api *MockedApi) {
+				api.On("uploadMedia", "ACCESS_TOKEN", "photo.jpg", mock.Anything).Return("upload-token", nil).Once()
+				api.On("createMediaItem", "ACCESS_TOKEN", "album", "upload-token", "photo.jpg", "desc").Return(photo, nil).Once()
+			},
+			want: photo,
+		},
+		{
+			name: "unauthorizedErr & retry upload",
+			setup: func(api *MockedApi) {
+				api.On("uploadMedia", "ACCESS_TOKEN", "photo.jpg", mock.Anything).Return("", unauthorizedErr).Once()
+				api.On("refreshAccessToken", mock.Anything, mock.Anything, mock.Anything).Return("NEW_TOKEN", nil).Once()
+				api.On("uploadMedia", "NEW_TOKEN", "photo.jpg", mock.Anything).Return("upload-token", nil).Once()
+				api.On("createMediaItem", "NEW_TOKEN", "album", "upload-token", "photo.jpg", "desc").Return(photo, nil).Once()
+			},
+			want: photo,
+		},
+		{
+			name: "create media item fail",
+			setup: func(api *MockedApi) {
+				api.On("uploadMedia", "ACCESS_TOKEN", "photo.jpg", mock.Anything).Return("upload-token", nil).Once()
+				api.On("createMediaItem", "ACCESS_TOKEN", "album", "upload-token", "photo.jpg", "desc").Return(nil, someErr).Once()
+			},
+			wantErr: uploadErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := new(MockedApi)
+			tt.setup(api)
+			c := &Client{accessToken: "ACCESS_TOKEN", api: api}
+
+			got, err := c.Upload("album", "photo.jpg", "desc", strings.NewReader("content"))
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+			api.AssertExpectations(t)
+		})
+	}
+}